@@ -9,8 +9,12 @@ import (
 	"syscall"
 	"time"
 
+	"mini-product-catalog/internal/auth"
 	"mini-product-catalog/internal/config"
 	"mini-product-catalog/internal/http"
+	"mini-product-catalog/internal/migrate"
+	"mini-product-catalog/internal/money"
+	"mini-product-catalog/migrations"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -23,6 +27,11 @@ func main() {
 	}))
 	slog.SetDefault(logger)
 
+	if err := money.Configure(cfg.DefaultCurrency, cfg.MoneyFormat); err != nil {
+		logger.Error("invalid money settings", "err", err)
+		os.Exit(1)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -39,7 +48,31 @@ func main() {
 	}
 	logger.Info("db connected")
 
-	handler := http.NewServer(cfg, logger, db)
+	if cfg.MigrateOnStart {
+		m, err := migrate.New(db, migrations.FS, logger)
+		if err != nil {
+			logger.Error("failed to load migrations", "err", err)
+			os.Exit(1)
+		}
+
+		n, err := m.Up(context.Background())
+		if err != nil {
+			logger.Error("failed to run migrations", "err", err)
+			os.Exit(1)
+		}
+		logger.Info("migrations up to date", "applied", n)
+	}
+
+	keys := auth.NewHMACKeySet(cfg.JWTSecret, cfg.JWTIssuer)
+	if cfg.JWTSigningKeys != "" {
+		keys, err = auth.LoadKeySet(cfg.JWTSigningKeys, cfg.AccessTokenTTL, cfg.JWTIssuer)
+		if err != nil {
+			logger.Error("failed to load jwt signing keys", "err", err)
+			os.Exit(1)
+		}
+	}
+
+	handler := http.NewServer(cfg, logger, db, keys)
 
 	srv := &nethttp.Server{
 		Addr:         ":" + cfg.Port,
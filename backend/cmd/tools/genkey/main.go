@@ -0,0 +1,46 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+)
+
+func main() {
+	kind := "ed25519"
+
+	if len(os.Args) > 1 {
+		kind = os.Args[1]
+	}
+
+	var key any
+	var err error
+
+	switch kind {
+	case "rsa":
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "ed25519":
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		fmt.Fprintln(os.Stderr, "usage: genkey [rsa|ed25519]")
+		os.Exit(2)
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+
+	if err != nil {
+		panic(err)
+	}
+
+	if err := pem.Encode(os.Stdout, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"mini-product-catalog/internal/config"
+	"mini-product-catalog/internal/migrate"
+	"mini-product-catalog/migrations"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const usage = `usage: migrate <command>
+
+commands:
+  up         apply all pending migrations
+  down [N]   roll back the last N applied migrations (default 1)
+  status     list migrations and when they were applied
+  goto N     migrate up or down to version N (0 rolls back everything)
+  baseline N record versions up to N as applied without running them
+
+A database whose schema was applied by hand has tables but no recorded
+versions, and up refuses to run on it. Run baseline with the last file that
+was applied (for example "baseline 4"), then up as usual.`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg := config.Load()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+	}))
+
+	ctx := context.Background()
+
+	db, err := pgxpool.New(ctx, cfg.DBURL)
+	if err != nil {
+		fail("failed to create db pool", err)
+	}
+	defer db.Close()
+
+	m, err := migrate.New(db, migrations.FS, logger)
+	if err != nil {
+		fail("failed to load migrations", err)
+	}
+
+	switch os.Args[1] {
+	case "up":
+		n, err := m.Up(ctx)
+		if err != nil {
+			fail("migrate up failed", err)
+		}
+		fmt.Printf("applied %d migration(s)\n", n)
+
+	case "down":
+		steps := 1
+		if len(os.Args) > 2 {
+			steps, err = strconv.Atoi(os.Args[2])
+			if err != nil || steps < 1 {
+				fail("invalid step count", fmt.Errorf("%q", os.Args[2]))
+			}
+		}
+		n, err := m.Down(ctx, steps)
+		if err != nil {
+			fail("migrate down failed", err)
+		}
+		fmt.Printf("rolled back %d migration(s)\n", n)
+
+	case "status":
+		items, err := m.Status(ctx)
+		if err != nil {
+			fail("migrate status failed", err)
+		}
+		for _, st := range items {
+			applied := "pending"
+			if st.AppliedAt != nil {
+				applied = st.AppliedAt.Format(time.RFC3339)
+			}
+			name := st.Name
+			if name == "" {
+				name = "(missing from binary)"
+			}
+			fmt.Printf("%04d  %-30s  %s\n", st.Version, name, applied)
+		}
+
+	case "goto":
+		if len(os.Args) < 3 {
+			fail("goto requires a version", nil)
+		}
+		version, err := strconv.ParseInt(os.Args[2], 10, 64)
+		if err != nil || version < 0 {
+			fail("invalid version", fmt.Errorf("%q", os.Args[2]))
+		}
+		n, err := m.Goto(ctx, version)
+		if err != nil {
+			fail("migrate goto failed", err)
+		}
+		fmt.Printf("applied or rolled back %d migration(s)\n", n)
+
+	case "baseline":
+		if len(os.Args) < 3 {
+			fail("baseline requires a version", nil)
+		}
+		version, err := strconv.ParseInt(os.Args[2], 10, 64)
+		if err != nil || version < 1 {
+			fail("invalid version", fmt.Errorf("%q", os.Args[2]))
+		}
+		n, err := m.Baseline(ctx, version)
+		if err != nil {
+			fail("migrate baseline failed", err)
+		}
+		fmt.Printf("recorded %d migration(s)\n", n)
+
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func fail(msg string, err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", msg, err)
+	} else {
+		fmt.Fprintln(os.Stderr, msg)
+	}
+	os.Exit(1)
+}
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+)
+
+// API keys look like "mpc_<prefix>_<secret>". The prefix is stored in clear
+// so a key can be looked up and recognised in logs; the whole key is only
+// stored as a HashToken digest.
+const apiKeyTag = "mpc"
+
+var prefixEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+func GenerateAPIKey() (key, prefix string, err error) {
+	b := make([]byte, 5)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	prefix = prefixEncoding.EncodeToString(b)
+
+	secret, err := NewOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	return apiKeyTag + "_" + prefix + "_" + secret, prefix, nil
+}
+
+func APIKeyPrefix(key string) (string, bool) {
+	tag, rest, ok := strings.Cut(key, "_")
+	if !ok || tag != apiKeyTag {
+		return "", false
+	}
+	prefix, secret, ok := strings.Cut(rest, "_")
+	if !ok || len(prefix) != 8 || secret == "" {
+		return "", false
+	}
+	return prefix, true
+}
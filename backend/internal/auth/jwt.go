@@ -8,37 +8,50 @@ import (
 	"github.com/google/uuid"
 )
 
+// Authentication methods recorded in the amr claim (RFC 8176).
+const (
+	AMRPassword = "pwd"
+	AMROTP      = "otp"
+)
+
+const mfaChallengeAudience = "mfa-challenge"
+
 type Claims struct {
-	Role string `json:"role"`
+	Role          string   `json:"role"`
+	SessionID     string   `json:"sid,omitempty"`
+	EmailVerified bool     `json:"email_verified"`
+	AMR           []string `json:"amr,omitempty"`
+	// TokenVersion must match users.token_version; bumping the column
+	// invalidates every access token issued before.
+	TokenVersion int `json:"ver"`
 	jwt.RegisteredClaims
 }
 
-func GenerateAccessToken(userID uuid.UUID, role string, secret string, ttl time.Duration) (string, time.Time, error) {
+func (c Claims) HasAMR(method string) bool {
+	for _, m := range c.AMR {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// GenerateAccessToken signs claims after filling in the issuer and the
+// issued-at and expiry times.
+func GenerateAccessToken(keys *KeySet, claims Claims, ttl time.Duration) (string, time.Time, error) {
 	now := time.Now()
 	exp := now.Add(ttl)
 
-	claims := Claims{
-		Role: role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			Subject:   userID.String(),
-			ExpiresAt: jwt.NewNumericDate(exp),
-			IssuedAt:  jwt.NewNumericDate(now),
-		},
-	}
+	claims.Issuer = keys.Issuer()
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(exp)
 
-	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signed, err := t.SignedString([]byte(secret))
+	signed, err := keys.Sign(claims)
 	return signed, exp, err
 }
 
-func ParseAccessToken(tokenStr string, secret string) (Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(t *jwt.Token) (any, error) {
-		if t.Method != jwt.SigningMethodHS256 {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(secret), nil
-	})
-
+func ParseAccessToken(keys *KeySet, tokenStr string) (Claims, error) {
+	token, err := keys.Parse(tokenStr, &Claims{})
 	if err != nil {
 		return Claims{}, err
 	}
@@ -47,6 +60,43 @@ func ParseAccessToken(tokenStr string, secret string) (Claims, error) {
 	if !ok || !token.Valid {
 		return Claims{}, errors.New("invalid token")
 	}
+	// Tokens minted for another purpose (e.g. MFA challenges) carry an
+	// audience and must never be accepted as access tokens.
+	if len(claims.Audience) > 0 {
+		return Claims{}, errors.New("invalid token")
+	}
 
 	return *claims, nil
 }
+
+// GenerateMFAChallenge returns a short-lived token proving that userID has
+// passed the password step of login.
+func GenerateMFAChallenge(keys *KeySet, userID uuid.UUID, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	exp := now.Add(ttl)
+
+	claims := jwt.RegisteredClaims{
+		Issuer:    keys.Issuer(),
+		Subject:   userID.String(),
+		Audience:  jwt.ClaimStrings{mfaChallengeAudience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(exp),
+	}
+
+	signed, err := keys.Sign(claims)
+	return signed, exp, err
+}
+
+func ParseMFAChallenge(keys *KeySet, tokenStr string) (uuid.UUID, error) {
+	token, err := keys.Parse(tokenStr, &jwt.RegisteredClaims{}, jwt.WithAudience(mfaChallengeAudience))
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	claims, ok := token.Claims.(*jwt.RegisteredClaims)
+	if !ok || !token.Valid {
+		return uuid.Nil, errors.New("invalid token")
+	}
+
+	return uuid.Parse(claims.Subject)
+}
@@ -0,0 +1,328 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type SigningKey struct {
+	ID     string
+	Method jwt.SigningMethod
+	// ActiveFrom is when the key starts signing new tokens. The previous key
+	// keeps verifying until ActiveFrom plus the token TTL has passed.
+	ActiveFrom time.Time
+
+	signKey   any
+	verifyKey any
+}
+
+type KeySet struct {
+	keys     []SigningKey
+	tokenTTL time.Duration
+	issuer   string
+	now      func() time.Time
+}
+
+// NewHMACKeySet returns a key set that signs and verifies with a single
+// shared HS256 secret.
+func NewHMACKeySet(secret, issuer string) *KeySet {
+	return &KeySet{
+		keys: []SigningKey{{
+			Method:    jwt.SigningMethodHS256,
+			signKey:   []byte(secret),
+			verifyKey: []byte(secret),
+		}},
+		issuer: issuer,
+		now:    time.Now,
+	}
+}
+
+// LoadKeySet parses a comma separated list of "kid:path[@activate-at]"
+// entries, where path is a PEM encoded RSA, Ed25519 or ECDSA P-256 private
+// key and activate-at is an RFC 3339 timestamp. Entries without a timestamp
+// are active immediately.
+func LoadKeySet(spec string, tokenTTL time.Duration, issuer string) (*KeySet, error) {
+	ks := &KeySet{tokenTTL: tokenTTL, issuer: issuer, now: time.Now}
+
+	seen := map[string]bool{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kid, rest, ok := strings.Cut(entry, ":")
+		if !ok || kid == "" {
+			return nil, fmt.Errorf("invalid signing key entry %q, want kid:path[@time]", entry)
+		}
+		if seen[kid] {
+			return nil, fmt.Errorf("duplicate signing key id %q", kid)
+		}
+		seen[kid] = true
+
+		path, at, _ := strings.Cut(rest, "@")
+		var activeFrom time.Time
+		if at != "" {
+			t, err := time.Parse(time.RFC3339, at)
+			if err != nil {
+				return nil, fmt.Errorf("invalid activation time for key %q: %w", kid, err)
+			}
+			activeFrom = t
+		}
+
+		pemBytes, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read signing key %q: %w", kid, err)
+		}
+
+		k, err := parsePrivateKey(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse signing key %q: %w", kid, err)
+		}
+		k.ID = kid
+		k.ActiveFrom = activeFrom
+
+		ks.keys = append(ks.keys, k)
+	}
+
+	if len(ks.keys) == 0 {
+		return nil, errors.New("no signing keys configured")
+	}
+
+	sort.SliceStable(ks.keys, func(i, j int) bool {
+		return ks.keys[i].ActiveFrom.Before(ks.keys[j].ActiveFrom)
+	})
+
+	return ks, nil
+}
+
+func parsePrivateKey(pemBytes []byte) (SigningKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return SigningKey{}, errors.New("no PEM block found")
+	}
+
+	var priv any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		priv, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		priv, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		priv, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return SigningKey{}, err
+	}
+
+	switch k := priv.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 2048 {
+			return SigningKey{}, errors.New("RSA keys must be at least 2048 bits")
+		}
+		return SigningKey{Method: jwt.SigningMethodRS256, signKey: k, verifyKey: &k.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return SigningKey{Method: jwt.SigningMethodEdDSA, signKey: k, verifyKey: k.Public()}, nil
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return SigningKey{}, errors.New("only P-256 ECDSA keys are supported")
+		}
+		return SigningKey{Method: jwt.SigningMethodES256, signKey: k, verifyKey: &k.PublicKey}, nil
+	default:
+		return SigningKey{}, fmt.Errorf("unsupported key type %T", priv)
+	}
+}
+
+// current returns the newest key that is already active.
+func (ks *KeySet) current() (SigningKey, int) {
+	now := ks.now()
+	idx := 0
+	for i, k := range ks.keys {
+		if !k.ActiveFrom.After(now) {
+			idx = i
+		}
+	}
+	return ks.keys[idx], idx
+}
+
+// verifying reports whether tokens signed by the key at index i may still be
+// accepted: the key is current, scheduled, or was replaced less than one
+// token lifetime ago.
+func (ks *KeySet) verifying(i int) bool {
+	_, cur := ks.current()
+	if i >= cur {
+		return true
+	}
+	retiredAt := ks.keys[i+1].ActiveFrom
+	return ks.now().Before(retiredAt.Add(ks.tokenTTL))
+}
+
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	k, _ := ks.current()
+
+	t := jwt.NewWithClaims(k.Method, claims)
+	if k.ID != "" {
+		t.Header["kid"] = k.ID
+	}
+	return t.SignedString(k.signKey)
+}
+
+func (ks *KeySet) Issuer() string {
+	return ks.issuer
+}
+
+func (ks *KeySet) Parse(tokenStr string, claims jwt.Claims, extra ...jwt.ParserOption) (*jwt.Token, error) {
+	opts := append([]jwt.ParserOption{jwt.WithExpirationRequired()}, extra...)
+	if ks.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(ks.issuer))
+	}
+
+	return jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+
+		for i, k := range ks.keys {
+			if k.ID != kid {
+				continue
+			}
+			if t.Method != k.Method {
+				return nil, errors.New("unexpected signing method")
+			}
+			if !ks.verifying(i) {
+				return nil, errors.New("signing key retired")
+			}
+			return k.verifyKey, nil
+		}
+		return nil, errors.New("unknown signing key")
+	}, opts...)
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys that verifiers should currently trust,
+// including keys scheduled to become active. Symmetric keys are never
+// published.
+func (ks *KeySet) JWKS() JWKS {
+	out := JWKS{Keys: []JWK{}}
+	for i, k := range ks.keys {
+		if !ks.verifying(i) {
+			continue
+		}
+		if jwk, ok := publicJWK(k); ok {
+			out.Keys = append(out.Keys, jwk)
+		}
+	}
+	return out
+}
+
+func publicJWK(k SigningKey) (JWK, bool) {
+	b64 := base64.RawURLEncoding.EncodeToString
+	jwk := JWK{Kid: k.ID, Use: "sig", Alg: k.Method.Alg()}
+
+	switch pub := k.verifyKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = b64(pub.N.Bytes())
+		jwk.E = b64(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = b64(pub)
+	case *ecdsa.PublicKey:
+		raw, err := pub.Bytes()
+		if err != nil {
+			return JWK{}, false
+		}
+		// Uncompressed point: 0x04 || X || Y, each 32 bytes for P-256.
+		jwk.Kty = "EC"
+		jwk.Crv = "P-256"
+		jwk.X = b64(raw[1:33])
+		jwk.Y = b64(raw[33:65])
+	default:
+		return JWK{}, false
+	}
+
+	return jwk, true
+}
+
+// PublicKeyFromJWK is the inverse of the encoding used by JWKS, for
+// verifying tokens signed by other issuers.
+func PublicKeyFromJWK(jwk JWK) (any, error) {
+	b64 := base64.RawURLEncoding.DecodeString
+
+	switch jwk.Kty {
+	case "RSA":
+		n, err := b64(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := b64(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		eInt := new(big.Int).SetBytes(e)
+		if !eInt.IsInt64() || eInt.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(eInt.Int64())}, nil
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported OKP curve %q", jwk.Crv)
+		}
+		x, err := b64(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key length")
+		}
+		return ed25519.PublicKey(x), nil
+	case "EC":
+		if jwk.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported EC curve %q", jwk.Crv)
+		}
+		x, err := b64(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := b64(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != 32 || len(y) != 32 {
+			return nil, errors.New("invalid P-256 coordinate length")
+		}
+		raw := append(append([]byte{4}, x...), y...)
+		return ecdsa.ParseUncompressedPublicKey(elliptic.P256(), raw)
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+}
@@ -0,0 +1,30 @@
+package auth
+
+import "time"
+
+type LockoutPolicy struct {
+	// MaxAttempts is how many failures within Window are tolerated before
+	// the key is locked.
+	MaxAttempts int
+	Window      time.Duration
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// LockFor returns how long to lock a key after the given number of
+// consecutive failures. The delay doubles with every failure past
+// MaxAttempts, up to MaxDelay.
+func (p LockoutPolicy) LockFor(failures int) time.Duration {
+	if p.MaxAttempts <= 0 || failures < p.MaxAttempts {
+		return 0
+	}
+
+	d := p.BaseDelay
+	for i := p.MaxAttempts; i < failures && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d
+}
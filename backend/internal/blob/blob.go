@@ -0,0 +1,19 @@
+// Package blob stores uploaded files such as product images.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore saves and serves opaque files by key. Keys are slash separated
+// paths such as "products/<id>/<image>/original.jpg".
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	Delete(ctx context.Context, key string) error
+	// URL is where clients can fetch the blob.
+	URL(key string) string
+}
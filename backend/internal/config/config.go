@@ -2,14 +2,77 @@ package config
 
 import (
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type Config struct {
-	Port           string
-	DBURL          string
-	JWTSecret      string
-	AllowedOrigins []string
+	Port            string
+	DBURL           string
+	JWTSecret       string
+	JWTSigningKeys  string
+	JWTIssuer       string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	AllowedOrigins  []string
+	MigrateOnStart  bool
+
+	AppBaseURL       string
+	PasswordResetTTL time.Duration
+
+	EmailVerification          string
+	EmailVerificationTTL       time.Duration
+	VerificationResendCooldown time.Duration
+
+	MFAIssuer            string
+	MFAChallengeTTL      time.Duration
+	MFARequiredForAdmins bool
+
+	LoginMaxAttempts   int
+	LoginIPMaxAttempts int
+	LoginFailureWindow time.Duration
+	LoginLockoutBase   time.Duration
+	LoginLockoutMax    time.Duration
+
+	MailDriver   string
+	MailFrom     string
+	MailDir      string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+
+	APIBaseURL    string
+	OIDCProviders []OIDCProvider
+
+	DefaultCurrency   string
+	MoneyFormat       string
+	PriceFacetBuckets string
+
+	BlobDir       string
+	MediaBaseURL  string
+	MaxImageBytes int
+}
+
+type OIDCProvider struct {
+	Name         string
+	DisplayName  string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	GroupsClaim  string
+	RoleMappings []OIDCRoleMapping
+	DefaultRole  string
+	AllowSignup  bool
+}
+
+// OIDCRoleMapping grants Role to users in Group. Mappings are checked in
+// the order they were configured and the first match wins.
+type OIDCRoleMapping struct {
+	Group string
+	Role  string
 }
 
 func Load() Config {
@@ -21,11 +84,86 @@ func Load() Config {
 	origins := splitAndTrim(originsRaw)
 
 	return Config{
-		Port:           port,
-		DBURL:          dbURL,
-		JWTSecret:      jwt,
-		AllowedOrigins: origins,
+		Port:            port,
+		DBURL:           dbURL,
+		JWTSecret:       jwt,
+		JWTSigningKeys:  os.Getenv("JWT_SIGNING_KEYS"),
+		JWTIssuer:       getenv("JWT_ISSUER", "mini-product-catalog"),
+		AccessTokenTTL:  getenvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: getenvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		AllowedOrigins:  origins,
+		MigrateOnStart:  getenvBool("MIGRATE_ON_START", false),
+
+		AppBaseURL:       getenv("APP_BASE_URL", "http://localhost:5173"),
+		PasswordResetTTL: getenvDuration("PASSWORD_RESET_TTL", time.Hour),
+
+		EmailVerification:          getenv("EMAIL_VERIFICATION", "off"),
+		EmailVerificationTTL:       getenvDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+		VerificationResendCooldown: getenvDuration("VERIFICATION_RESEND_COOLDOWN", time.Minute),
+
+		MFAIssuer:            getenv("MFA_ISSUER", "Mini Product Catalog"),
+		MFAChallengeTTL:      getenvDuration("MFA_CHALLENGE_TTL", 5*time.Minute),
+		MFARequiredForAdmins: getenvBool("MFA_REQUIRED_FOR_ADMINS", false),
+
+		LoginMaxAttempts:   getenvInt("LOGIN_MAX_ATTEMPTS", 5),
+		LoginIPMaxAttempts: getenvInt("LOGIN_IP_MAX_ATTEMPTS", 20),
+		LoginFailureWindow: getenvDuration("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+		LoginLockoutBase:   getenvDuration("LOGIN_LOCKOUT_BASE", time.Minute),
+		LoginLockoutMax:    getenvDuration("LOGIN_LOCKOUT_MAX", time.Hour),
+
+		MailDriver:   getenv("MAIL_DRIVER", "dir"),
+		MailFrom:     getenv("MAIL_FROM", "Mini Catalog <no-reply@localhost>"),
+		MailDir:      getenv("MAIL_DIR", "./tmp/mail"),
+		SMTPHost:     getenv("SMTP_HOST", "localhost"),
+		SMTPPort:     getenvInt("SMTP_PORT", 587),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+
+		APIBaseURL:    getenv("API_BASE_URL", "http://localhost:"+port),
+		OIDCProviders: loadOIDCProviders(),
+
+		DefaultCurrency:   getenv("DEFAULT_CURRENCY", "IDR"),
+		MoneyFormat:       getenv("MONEY_FORMAT", "object"),
+		PriceFacetBuckets: getenv("PRICE_FACET_BUCKETS", "100000,250000,500000,1000000"),
+
+		BlobDir:       getenv("BLOB_DIR", "./tmp/blobs"),
+		MediaBaseURL:  getenv("MEDIA_BASE_URL", getenv("API_BASE_URL", "http://localhost:"+port)+"/media"),
+		MaxImageBytes: getenvInt("IMAGE_MAX_BYTES", 10<<20),
+	}
+}
+
+// loadOIDCProviders reads OIDC_PROVIDERS=name1,name2 and, for each name, the
+// OIDC_<NAME>_* variables describing that provider.
+func loadOIDCProviders() []OIDCProvider {
+	var out []OIDCProvider
+	for _, name := range splitAndTrim(os.Getenv("OIDC_PROVIDERS")) {
+		name = strings.ToLower(name)
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+
+		var mappings []OIDCRoleMapping
+		for _, m := range splitAndTrim(os.Getenv(prefix + "ROLE_MAP")) {
+			group, role, ok := strings.Cut(m, "=")
+			if !ok {
+				continue
+			}
+			mappings = append(mappings, OIDCRoleMapping{Group: strings.TrimSpace(group), Role: strings.TrimSpace(role)})
+		}
+
+		out = append(out, OIDCProvider{
+			Name:         name,
+			DisplayName:  getenv(prefix+"DISPLAY_NAME", name),
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			Scopes:       splitAndTrim(getenv(prefix+"SCOPES", "openid,email,profile")),
+			GroupsClaim:  getenv(prefix+"GROUPS_CLAIM", "groups"),
+			RoleMappings: mappings,
+			DefaultRole:  getenv(prefix+"DEFAULT_ROLE", "user"),
+			AllowSignup:  getenvBool(prefix+"ALLOW_SIGNUP", true),
+		})
 	}
+
+	return out
 }
 
 func getenv(key, def string) string {
@@ -37,6 +175,33 @@ func getenv(key, def string) string {
 	return v
 }
 
+func getenvBool(key string, def bool) bool {
+	v, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+
+	return v
+}
+
+func getenvInt(key string, def int) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+
+	return v
+}
+
+func getenvDuration(key string, def time.Duration) time.Duration {
+	v, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || v <= 0 {
+		return def
+	}
+
+	return v
+}
+
 func splitAndTrim(s string) []string {
 	parts := strings.Split(s, ",")
 	out := make([]string, 0, len(parts))
@@ -0,0 +1,230 @@
+package handler
+
+import (
+	"errors"
+	"mini-product-catalog/internal/middleware"
+	"mini-product-catalog/internal/model"
+	"mini-product-catalog/internal/response"
+	"mini-product-catalog/internal/store"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type AdminUsersHandler struct {
+	users         *store.UserStore
+	refreshTokens *store.RefreshTokenStore
+	throttles     *store.LoginThrottleStore
+	roles         *store.RoleStore
+	validate      *validator.Validate
+}
+
+func NewAdminUsersHandler(users *store.UserStore, refreshTokens *store.RefreshTokenStore, throttles *store.LoginThrottleStore, roles *store.RoleStore, validate *validator.Validate) *AdminUsersHandler {
+	return &AdminUsersHandler{
+		users:         users,
+		refreshTokens: refreshTokens,
+		throttles:     throttles,
+		roles:         roles,
+		validate:      validate,
+	}
+}
+
+func (h *AdminUsersHandler) List(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	opt := store.UserListOptions{
+		Page:  parseInt(q.Get("page"), 1),
+		Limit: parseInt(q.Get("limit"), 20),
+		Q:     q.Get("q"),
+		Role:  strings.TrimSpace(q.Get("role")),
+	}
+	if v := strings.TrimSpace(q.Get("disabled")); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			response.WriteError(w, http.StatusBadRequest, "invalid disabled", nil)
+			return
+		}
+		opt.Disabled = &b
+	}
+
+	items, total, err := h.users.List(r.Context(), opt)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch users", nil)
+		return
+	}
+
+	meta := map[string]any{
+		"page":  opt.Page,
+		"limit": opt.Limit,
+		"total": total,
+	}
+
+	response.WriteData(w, http.StatusOK, items, meta)
+}
+
+func (h *AdminUsersHandler) Get(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid user id", nil)
+		return
+	}
+
+	u, err := h.users.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.WriteError(w, http.StatusNotFound, "user not found", nil)
+			return
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch user", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, u, nil)
+}
+
+func (h *AdminUsersHandler) Update(w http.ResponseWriter, r *http.Request) {
+	cur, ok := middleware.CurrentUserFromContext(r.Context())
+	if !ok {
+		response.WriteError(w, http.StatusUnauthorized, "unauthorized", nil)
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid user id", nil)
+		return
+	}
+
+	var req model.AdminUserUpdateRequest
+	if err := response.DecodeJSON(w, r, &req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		req.Name = &name
+	}
+	if req.Role != nil {
+		role := strings.TrimSpace(strings.ToLower(*req.Role))
+		req.Role = &role
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return
+	}
+
+	if id == cur.ID && (req.Role != nil || req.Disabled != nil) {
+		response.WriteError(w, http.StatusForbidden, "you cannot change your own role or status", nil)
+		return
+	}
+
+	if req.Role != nil {
+		perms, err := h.roles.Permissions(r.Context(), cur.Role)
+		if err != nil {
+			response.WriteError(w, http.StatusInternalServerError, "failed to resolve permissions", nil)
+			return
+		}
+		if !slices.Contains(perms, model.PermissionRolesManage) {
+			response.WriteError(w, http.StatusForbidden, "forbidden", map[string]string{
+				"permission": model.PermissionRolesManage,
+			})
+			return
+		}
+	}
+
+	updated, err := h.users.Update(r.Context(), id, store.UserUpdate{
+		Name:     req.Name,
+		Role:     req.Role,
+		Disabled: req.Disabled,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			response.WriteError(w, http.StatusNotFound, "user not found", nil)
+		case errors.Is(err, store.ErrRoleNotFound):
+			response.WriteError(w, http.StatusBadRequest, "unknown role", nil)
+		default:
+			response.WriteError(w, http.StatusInternalServerError, "failed to update user", nil)
+		}
+		return
+	}
+
+	if req.Disabled != nil && *req.Disabled {
+		if err := h.refreshTokens.RevokeAllForUser(r.Context(), id); err != nil {
+			response.WriteError(w, http.StatusInternalServerError, "failed to revoke sessions", nil)
+			return
+		}
+	}
+
+	response.WriteData(w, http.StatusOK, updated, nil)
+}
+
+func (h *AdminUsersHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	cur, ok := middleware.CurrentUserFromContext(r.Context())
+	if !ok {
+		response.WriteError(w, http.StatusUnauthorized, "unauthorized", nil)
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid user id", nil)
+		return
+	}
+
+	if id == cur.ID {
+		response.WriteError(w, http.StatusForbidden, "you cannot delete your own account here", nil)
+		return
+	}
+
+	deleted, err := h.users.Delete(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.WriteError(w, http.StatusNotFound, "user not found", nil)
+			return
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to delete user", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, deleted, nil)
+}
+
+func (h *AdminUsersHandler) Unlock(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid user id", nil)
+		return
+	}
+
+	u, err := h.users.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.WriteError(w, http.StatusNotFound, "user not found", nil)
+			return
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch user", nil)
+		return
+	}
+
+	for _, key := range []string{"email:" + u.Email, "mfa:" + u.ID.String()} {
+		if err := h.throttles.Reset(r.Context(), key); err != nil {
+			response.WriteError(w, http.StatusInternalServerError, "failed to unlock user", nil)
+			return
+		}
+	}
+
+	response.WriteData(w, http.StatusOK, u, nil)
+}
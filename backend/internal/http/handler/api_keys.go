@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"errors"
+	"mini-product-catalog/internal/auth"
+	"mini-product-catalog/internal/middleware"
+	"mini-product-catalog/internal/model"
+	"mini-product-catalog/internal/response"
+	"mini-product-catalog/internal/store"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type APIKeysHandler struct {
+	store    *store.APIKeyStore
+	validate *validator.Validate
+}
+
+func NewAPIKeysHandler(store *store.APIKeyStore, validate *validator.Validate) *APIKeysHandler {
+	return &APIKeysHandler{store: store, validate: validate}
+}
+
+func (h *APIKeysHandler) List(w http.ResponseWriter, r *http.Request) {
+	items, err := h.store.List(r.Context())
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch api keys", nil)
+		return
+	}
+
+	meta := map[string]any{
+		"count": len(items),
+	}
+	response.WriteData(w, http.StatusOK, items, meta)
+}
+
+func (h *APIKeysHandler) Create(w http.ResponseWriter, r *http.Request) {
+	cur, ok := middleware.CurrentUserFromContext(r.Context())
+	if !ok {
+		response.WriteError(w, http.StatusUnauthorized, "unauthorized", nil)
+		return
+	}
+
+	var req model.APIKeyCreateRequest
+	if err := response.DecodeJSON(w, r, &req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+
+	if err := h.validate.Struct(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		response.WriteError(w, http.StatusBadRequest, "expires_at must be in the future", nil)
+		return
+	}
+
+	key, prefix, err := auth.GenerateAPIKey()
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to generate api key", nil)
+		return
+	}
+
+	created, err := h.store.Create(r.Context(), req.Name, prefix, auth.HashToken(key), req.Scopes, cur.ID, req.ExpiresAt)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to create api key", nil)
+		return
+	}
+
+	// The plaintext key is only returned once, at creation.
+	response.WriteData(w, http.StatusCreated, map[string]any{
+		"api_key": created,
+		"key":     key,
+	}, nil)
+}
+
+func (h *APIKeysHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid api key id", nil)
+		return
+	}
+
+	revoked, err := h.store.Revoke(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.WriteError(w, http.StatusNotFound, "api key not found", nil)
+			return
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to revoke api key", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, revoked, nil)
+}
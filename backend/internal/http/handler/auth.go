@@ -1,10 +1,14 @@
 package handler
 
 import (
+	"context"
 	"errors"
+	"log/slog"
 	"mini-product-catalog/internal/auth"
+	"mini-product-catalog/internal/mail"
 	"mini-product-catalog/internal/middleware"
 	"mini-product-catalog/internal/model"
+	"mini-product-catalog/internal/oidc"
 	"mini-product-catalog/internal/response"
 	"mini-product-catalog/internal/store"
 	"net/http"
@@ -12,21 +16,61 @@ import (
 	"time"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
+type AuthConfig struct {
+	Keys             *auth.KeySet
+	AccessTokenTTL   time.Duration
+	RefreshTokenTTL  time.Duration
+	PasswordResetTTL time.Duration
+	AppBaseURL       string
+
+	// EmailVerification is "off", "login" (unverified users cannot log in)
+	// or "routes" (they can log in, but selected routes require verification).
+	EmailVerification          string
+	EmailVerificationTTL       time.Duration
+	VerificationResendCooldown time.Duration
+
+	MFAIssuer       string
+	MFAChallengeTTL time.Duration
+	// MFARequiredForStaff forbids users whose role grants any permission
+	// from turning two-factor authentication off.
+	MFARequiredForStaff bool
+
+	AccountLockout auth.LockoutPolicy
+	IPLockout      auth.LockoutPolicy
+
+	OIDCProviders map[string]*oidc.Provider
+}
+
 type AuthHandler struct {
-	users     *store.UserStore
-	validate  *validator.Validate
-	jwtSecret string
+	users         *store.UserStore
+	refreshTokens *store.RefreshTokenStore
+	userTokens    *store.UserTokenStore
+	mfa           *store.MFAStore
+	throttles     *store.LoginThrottleStore
+	roles         *store.RoleStore
+	identities    *store.OIDCStore
+	mailer        mail.Mailer
+	validate      *validator.Validate
+	cfg           AuthConfig
 }
 
-func NewAuthHandler(users *store.UserStore, validate *validator.Validate, jwtSecret string) *AuthHandler {
+func NewAuthHandler(users *store.UserStore, refreshTokens *store.RefreshTokenStore, userTokens *store.UserTokenStore, mfa *store.MFAStore, throttles *store.LoginThrottleStore, roles *store.RoleStore, identities *store.OIDCStore, mailer mail.Mailer, validate *validator.Validate, cfg AuthConfig) *AuthHandler {
 	return &AuthHandler{
-		users:     users,
-		validate:  validate,
-		jwtSecret: jwtSecret,
+		users:         users,
+		refreshTokens: refreshTokens,
+		userTokens:    userTokens,
+		mfa:           mfa,
+		throttles:     throttles,
+		roles:         roles,
+		identities:    identities,
+		mailer:        mailer,
+		validate:      validate,
+		cfg:           cfg,
 	}
 }
 
@@ -60,6 +104,10 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := h.sendVerification(r.Context(), created); err != nil {
+		slog.Error("failed to send verification email", "user_id", created.ID, "err", err)
+	}
+
 	response.WriteData(w, http.StatusCreated, created, nil)
 }
 
@@ -77,37 +125,193 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Accounts are throttled by email rather than user id so that unknown
+	// emails lock out exactly like real ones.
+	accountKey := "email:" + req.Email
+	ipKey := "ip:" + clientIP(r)
+
+	if h.checkLocked(w, r, accountKey, ipKey) {
+		return
+	}
+
 	u, err := h.users.GetByEmail(r.Context(), req.Email)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		response.WriteError(w, http.StatusInternalServerError, "failed to login", nil)
+		return
+	}
+
+	passwordHash := u.PasswordHash
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			response.WriteError(w, http.StatusUnauthorized, "invalid credentials", nil)
+		// Spend the same bcrypt time as for a real account.
+		passwordHash = dummyPasswordHash
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(req.Password)) != nil || err != nil {
+		h.recordFailure(r.Context(), accountKey, h.cfg.AccountLockout)
+		h.recordFailure(r.Context(), ipKey, h.cfg.IPLockout)
+		response.WriteError(w, http.StatusUnauthorized, "invalid credentials", nil)
+		return
+	}
+
+	if err := h.throttles.Reset(r.Context(), accountKey); err != nil {
+		slog.Error("failed to reset login throttle", "key", accountKey, "err", err)
+	}
+
+	h.completeLogin(w, r, u)
+}
+
+// completeLogin runs the checks shared by every first-factor login method and
+// answers with either an MFA challenge or a new session.
+func (h *AuthHandler) completeLogin(w http.ResponseWriter, r *http.Request, u model.User) {
+	if u.DisabledAt != nil {
+		response.WriteError(w, http.StatusForbidden, "account disabled", nil)
+		return
+	}
+
+	if h.cfg.EmailVerification == "login" && u.EmailVerifiedAt == nil {
+		response.WriteError(w, http.StatusForbidden, "email not verified", nil)
+		return
+	}
+
+	if u.MFAEnabledAt != nil {
+		challenge, exp, err := auth.GenerateMFAChallenge(h.cfg.Keys, u.ID, h.cfg.MFAChallengeTTL)
+		if err != nil {
+			response.WriteError(w, http.StatusInternalServerError, "failed to generate token", nil)
 			return
 		}
-		response.WriteError(w, http.StatusInternalServerError, "failed to login", nil)
+
+		response.WriteData(w, http.StatusOK, map[string]any{
+			"mfa_required": true,
+			"mfa_token":    challenge,
+			"expires_at":   exp,
+		}, nil)
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(req.Password)); err != nil {
-		response.WriteError(w, http.StatusUnauthorized, "invalid credentials", nil)
+	resp, err := h.startSession(r.Context(), u, false)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to generate token", nil)
 		return
 	}
 
-	ttl := 1 * time.Hour
-	token, exp, err := auth.GenerateAccessToken(u.ID, u.Role, h.jwtSecret, ttl)
+	response.WriteData(w, http.StatusOK, resp, nil)
+}
+
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req model.RefreshRequest
+	if err := response.DecodeJSON(w, r, &req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return
+	}
+
+	refreshToken, err := auth.NewOpaqueToken()
 	if err != nil {
 		response.WriteError(w, http.StatusInternalServerError, "failed to generate token", nil)
 		return
 	}
 
-	resp := map[string]any{
-		"access_token": token,
-		"token_type":   "Bearer",
-		"expires_at":   exp,
+	rt, err := h.refreshTokens.Rotate(r.Context(), auth.HashToken(req.RefreshToken), auth.HashToken(refreshToken), time.Now().Add(h.cfg.RefreshTokenTTL))
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrRefreshTokenReused):
+			response.WriteError(w, http.StatusUnauthorized, "refresh token reuse detected, session revoked", nil)
+		case errors.Is(err, store.ErrRefreshTokenInvalid), errors.Is(err, store.ErrRefreshTokenExpired):
+			response.WriteError(w, http.StatusUnauthorized, "invalid refresh token", nil)
+		default:
+			response.WriteError(w, http.StatusInternalServerError, "failed to refresh token", nil)
+		}
+		return
+	}
+
+	u, err := h.users.GetByID(r.Context(), rt.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.WriteError(w, http.StatusUnauthorized, "invalid refresh token", nil)
+			return
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to refresh token", nil)
+		return
+	}
+	if u.DisabledAt != nil {
+		response.WriteError(w, http.StatusUnauthorized, "account disabled", nil)
+		return
+	}
+
+	resp, err := h.tokenResponse(u, rt, refreshToken)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to generate token", nil)
+		return
 	}
 
 	response.WriteData(w, http.StatusOK, resp, nil)
 }
 
+// Logout revokes the session the refresh token belongs to. Access tokens
+// issued for it are rejected from then on, see middleware.AuthMiddleware.
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req model.LogoutRequest
+	if err := response.DecodeJSON(w, r, &req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return
+	}
+
+	if _, err := h.refreshTokens.RevokeByHash(r.Context(), auth.HashToken(req.RefreshToken)); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		response.WriteError(w, http.StatusInternalServerError, "failed to logout", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, map[string]string{"status": "logged out"}, nil)
+}
+
+func (h *AuthHandler) startSession(ctx context.Context, u model.User, mfa bool) (map[string]any, error) {
+	refreshToken, err := auth.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	rt, err := h.refreshTokens.Create(ctx, u.ID, uuid.New(), mfa, auth.HashToken(refreshToken), time.Now().Add(h.cfg.RefreshTokenTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	return h.tokenResponse(u, rt, refreshToken)
+}
+
+func (h *AuthHandler) tokenResponse(u model.User, rt model.RefreshToken, refreshToken string) (map[string]any, error) {
+	claims := auth.Claims{
+		Role:          u.Role,
+		SessionID:     rt.FamilyID.String(),
+		EmailVerified: u.EmailVerifiedAt != nil,
+		AMR:           []string{auth.AMRPassword},
+		TokenVersion:  u.TokenVersion,
+	}
+	if rt.MFA {
+		claims.AMR = append(claims.AMR, auth.AMROTP)
+	}
+	claims.Subject = u.ID.String()
+
+	token, exp, err := auth.GenerateAccessToken(h.cfg.Keys, claims, h.cfg.AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"access_token":       token,
+		"token_type":         "Bearer",
+		"expires_at":         exp,
+		"refresh_token":      refreshToken,
+		"refresh_expires_at": rt.ExpiresAt,
+	}, nil
+}
+
 func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
 	cur, ok := middleware.CurrentUserFromContext(r.Context())
 	if !ok {
@@ -125,5 +329,11 @@ func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	u.Permissions, err = h.roles.Permissions(r.Context(), u.Role)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch profile", nil)
+		return
+	}
+
 	response.WriteData(w, http.StatusOK, u, nil)
 }
@@ -2,10 +2,13 @@ package handler
 
 import (
 	"errors"
+	"mini-product-catalog/internal/middleware"
 	"mini-product-catalog/internal/model"
 	"mini-product-catalog/internal/response"
 	"mini-product-catalog/internal/store"
 	"net/http"
+	"regexp"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
@@ -13,6 +16,8 @@ import (
 	"github.com/jackc/pgx/v5"
 )
 
+var categorySlugRe = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
 type CategoriesHandler struct {
 	store    *store.CategoryStore
 	validate *validator.Validate
@@ -22,8 +27,19 @@ func NewCategoriesHandler(store *store.CategoryStore, validate *validator.Valida
 	return &CategoriesHandler{store: store, validate: validate}
 }
 
+// includeHidden reports whether the caller asked for hidden categories, or
+// products in them, and was authenticated for it. The public read routes only
+// authenticate include_hidden=true requests, and require categories:read or
+// products:read when they do.
+func includeHidden(r *http.Request) bool {
+	_, ok := middleware.CurrentUserFromContext(r.Context())
+	return ok && r.URL.Query().Get("include_hidden") == "true"
+}
+
+// List, Tree and GetBySlug leave hidden categories and their subcategories
+// out unless includeHidden.
 func (h *CategoriesHandler) List(w http.ResponseWriter, r *http.Request) {
-	items, err := h.store.List(r.Context())
+	items, err := h.store.List(r.Context(), includeHidden(r))
 	if err != nil {
 		response.WriteError(w, http.StatusInternalServerError, "failed to fetch categories", nil)
 		return
@@ -35,6 +51,30 @@ func (h *CategoriesHandler) List(w http.ResponseWriter, r *http.Request) {
 	response.WriteData(w, http.StatusOK, items, meta)
 }
 
+func (h *CategoriesHandler) Tree(w http.ResponseWriter, r *http.Request) {
+	roots, err := h.store.Tree(r.Context(), includeHidden(r))
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch categories", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, roots, nil)
+}
+
+func (h *CategoriesHandler) GetBySlug(w http.ResponseWriter, r *http.Request) {
+	c, err := h.store.GetBySlug(r.Context(), strings.ToLower(chi.URLParam(r, "slug")), includeHidden(r))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.WriteError(w, http.StatusNotFound, "category not found", nil)
+			return
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch category", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, c, nil)
+}
+
 func (h *CategoriesHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req model.CategoryCreateRequest
 
@@ -43,17 +83,36 @@ func (h *CategoriesHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	req.Slug = strings.ToLower(strings.TrimSpace(req.Slug))
+	req.Description = strings.TrimSpace(req.Description)
+	req.ImageURL = strings.TrimSpace(req.ImageURL)
+
 	if err := h.validate.Struct(req); err != nil {
 		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
 		return
 	}
 
-	created, err := h.store.Create(r.Context(), req.Name)
+	f, ok := categoryFields(w, req.Name, req.ParentID, req.Slug, req.Description, req.ImageURL)
+	if !ok {
+		return
+	}
+	f.IsVisible = req.IsVisible
+	f.Position = req.Position
+
+	created, err := h.store.Create(r.Context(), f)
 	if err != nil {
+		if errors.Is(err, store.ErrCategorySlugTaken) {
+			response.WriteError(w, http.StatusConflict, "slug already in use", nil)
+			return
+		}
 		if store.IsUniqueViolation(err) {
 			response.WriteError(w, http.StatusConflict, "category already exists", nil)
 			return
 		}
+		if store.IsForeignKeyViolation(err) {
+			response.WriteError(w, http.StatusBadRequest, "parent_id not found", nil)
+			return
+		}
 		response.WriteError(w, http.StatusInternalServerError, "failed to create category", nil)
 		return
 	}
@@ -74,21 +133,45 @@ func (h *CategoriesHandler) Update(w http.ResponseWriter, r *http.Request) {
 		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
 		return
 	}
+
+	req.Slug = strings.ToLower(strings.TrimSpace(req.Slug))
+	req.Description = strings.TrimSpace(req.Description)
+	req.ImageURL = strings.TrimSpace(req.ImageURL)
+
 	if err := h.validate.Struct(req); err != nil {
 		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
 		return
 	}
 
-	updated, err := h.store.Update(r.Context(), id, req.Name)
+	f, ok := categoryFields(w, req.Name, req.ParentID, req.Slug, req.Description, req.ImageURL)
+	if !ok {
+		return
+	}
+	f.IsVisible = req.IsVisible
+	f.Position = req.Position
+
+	updated, err := h.store.Update(r.Context(), id, f)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			response.WriteError(w, http.StatusNotFound, "category not found", nil)
 			return
 		}
+		if errors.Is(err, store.ErrCategoryCycle) {
+			response.WriteError(w, http.StatusConflict, "a category cannot be moved below itself or its subcategories", nil)
+			return
+		}
+		if errors.Is(err, store.ErrCategorySlugTaken) {
+			response.WriteError(w, http.StatusConflict, "slug already in use", nil)
+			return
+		}
 		if store.IsUniqueViolation(err) {
 			response.WriteError(w, http.StatusConflict, "category already exists", nil)
 			return
 		}
+		if store.IsForeignKeyViolation(err) {
+			response.WriteError(w, http.StatusBadRequest, "parent_id not found", nil)
+			return
+		}
 		response.WriteError(w, http.StatusInternalServerError, "failed to update category", nil)
 		return
 	}
@@ -111,7 +194,7 @@ func (h *CategoriesHandler) Delete(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		if store.IsForeignKeyViolation(err) {
-			response.WriteError(w, http.StatusConflict, "category is used by products", nil)
+			response.WriteError(w, http.StatusConflict, "category has products or subcategories", nil)
 			return
 		}
 		response.WriteError(w, http.StatusInternalServerError, "failed to delete category", nil)
@@ -120,3 +203,59 @@ func (h *CategoriesHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	response.WriteData(w, http.StatusOK, deleted, nil)
 }
+
+// Order sets the display order of the listed categories, usually all children
+// of one parent, to the order given. Either every position changes or none.
+func (h *CategoriesHandler) Order(w http.ResponseWriter, r *http.Request) {
+	var req model.CategoryOrderRequest
+	if err := response.DecodeJSON(w, r, &req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return
+	}
+
+	ids := make([]uuid.UUID, len(req.IDs))
+	for i, v := range req.IDs {
+		ids[i], _ = uuid.Parse(v)
+	}
+
+	if err := h.store.Reorder(r.Context(), ids); err != nil {
+		if errors.Is(err, store.ErrCategoryOrder) {
+			response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+			return
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to reorder categories", nil)
+		return
+	}
+
+	items, err := h.store.List(r.Context(), true)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch categories", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, items, map[string]any{"count": len(items)})
+}
+
+// categoryFields checks the parts of a create or update request the
+// validator can't and converts them for the store.
+func categoryFields(w http.ResponseWriter, name string, parentID *string, slug, description, imageURL string) (store.CategoryFields, bool) {
+	f := store.CategoryFields{Name: name, Slug: slug, Description: description}
+
+	if slug != "" && !categorySlugRe.MatchString(slug) {
+		response.WriteError(w, http.StatusBadRequest, "validation error", "slug may only contain lowercase letters and digits separated by single '-'")
+		return f, false
+	}
+	if parentID != nil {
+		id, _ := uuid.Parse(*parentID)
+		f.ParentID = &id
+	}
+	if imageURL != "" {
+		f.ImageURL = &imageURL
+	}
+
+	return f, true
+}
@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"mini-product-catalog/internal/model"
+	"mini-product-catalog/internal/response"
+	"mini-product-catalog/internal/store"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+var attributeKeyRe = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// attributeFilterOps are the range operators a product filter reads from an
+// "attr.<key>_<op>" suffix, so no attribute key may end in one.
+var attributeFilterOps = []string{"gte", "lte", "gt", "lt"}
+
+func validAttributeKey(key string) bool {
+	if !attributeKeyRe.MatchString(key) {
+		return false
+	}
+	for _, op := range attributeFilterOps {
+		if strings.HasSuffix(key, "_"+op) {
+			return false
+		}
+	}
+	return true
+}
+
+const maxAttributeTextLength = 500
+
+func (h *CategoriesHandler) ListAttributes(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid category id", nil)
+		return
+	}
+
+	items, err := h.store.ListAttributes(r.Context(), id)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch attributes", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, items, map[string]any{"count": len(items)})
+}
+
+func (h *CategoriesHandler) SetAttribute(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid category id", nil)
+		return
+	}
+	key := chi.URLParam(r, "key")
+	if !validAttributeKey(key) {
+		response.WriteError(w, http.StatusBadRequest, "invalid attribute key", "keys are lowercase letters, digits and '_', starting with a letter, and may not end in _gt, _gte, _lt or _lte")
+		return
+	}
+
+	var req model.CategoryAttributeRequest
+	if err := response.DecodeJSON(w, r, &req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+
+	req.Label = strings.TrimSpace(req.Label)
+	req.Unit = strings.TrimSpace(req.Unit)
+	for i := range req.Options {
+		req.Options[i] = strings.TrimSpace(req.Options[i])
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return
+	}
+	if req.Type == model.AttributeEnum {
+		values := slices.Clone(req.Options)
+		slices.Sort(values)
+		if len(values) == 0 || len(slices.Compact(values)) != len(req.Options) {
+			response.WriteError(w, http.StatusBadRequest, "validation error", "enum attributes need distinct options")
+			return
+		}
+	} else if len(req.Options) > 0 {
+		response.WriteError(w, http.StatusBadRequest, "validation error", "options only apply to enum attributes")
+		return
+	}
+	if req.Unit != "" && req.Type != model.AttributeNumber {
+		response.WriteError(w, http.StatusBadRequest, "validation error", "unit only applies to number attributes")
+		return
+	}
+
+	a := model.CategoryAttribute{
+		CategoryID: id,
+		Key:        key,
+		Label:      req.Label,
+		Type:       req.Type,
+		Options:    req.Options,
+		Required:   req.Required,
+		Position:   req.Position,
+	}
+	if req.Unit != "" {
+		a.Unit = &req.Unit
+	}
+	if a.Options == nil {
+		a.Options = []string{}
+	}
+
+	saved, err := h.store.SetAttribute(r.Context(), a)
+	if err != nil {
+		switch {
+		case store.IsForeignKeyViolation(err):
+			response.WriteError(w, http.StatusNotFound, "category not found", nil)
+		case errors.Is(err, store.ErrAttributeInUse):
+			response.WriteError(w, http.StatusConflict, "products hold values this change would invalidate", nil)
+		default:
+			response.WriteError(w, http.StatusInternalServerError, "failed to save attribute", nil)
+		}
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, saved, nil)
+}
+
+// DeleteAttribute also removes the attribute's values from the category's
+// products.
+func (h *CategoriesHandler) DeleteAttribute(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid category id", nil)
+		return
+	}
+
+	ok, err := h.store.DeleteAttribute(r.Context(), id, chi.URLParam(r, "key"))
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to delete attribute", nil)
+		return
+	}
+	if !ok {
+		response.WriteError(w, http.StatusNotFound, "attribute not found", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, map[string]string{"status": "deleted"}, nil)
+}
+
+// checkAttributes validates product attribute values against a category's
+// schema and returns them normalized. Null counts as not set.
+func checkAttributes(schema []model.CategoryAttribute, in map[string]any) (map[string]any, error) {
+	out := map[string]any{}
+	known := map[string]bool{}
+
+	for _, a := range schema {
+		known[a.Key] = true
+
+		v, ok := in[a.Key]
+		if !ok || v == nil {
+			if a.Required {
+				return nil, fmt.Errorf("attribute %s is required", a.Key)
+			}
+			continue
+		}
+
+		switch a.Type {
+		case model.AttributeText:
+			s, ok := v.(string)
+			if s = strings.TrimSpace(s); !ok || s == "" || len(s) > maxAttributeTextLength {
+				return nil, fmt.Errorf("attribute %s must be non-empty text of at most %d characters", a.Key, maxAttributeTextLength)
+			}
+			v = s
+		case model.AttributeNumber:
+			if _, ok := v.(float64); !ok {
+				return nil, fmt.Errorf("attribute %s must be a number", a.Key)
+			}
+		case model.AttributeEnum:
+			s, ok := v.(string)
+			if !ok || !slices.Contains(a.Options, s) {
+				return nil, fmt.Errorf("attribute %s must be one of %s", a.Key, strings.Join(a.Options, ", "))
+			}
+		case model.AttributeBoolean:
+			if _, ok := v.(bool); !ok {
+				return nil, fmt.Errorf("attribute %s must be true or false", a.Key)
+			}
+		}
+		out[a.Key] = v
+	}
+
+	for k := range in {
+		if !known[k] {
+			return nil, fmt.Errorf("unknown attribute %s for this category", k)
+		}
+	}
+
+	return out, nil
+}
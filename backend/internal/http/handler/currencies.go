@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"mini-product-catalog/internal/middleware"
+	"mini-product-catalog/internal/model"
+	"mini-product-catalog/internal/money"
+	"mini-product-catalog/internal/response"
+	"mini-product-catalog/internal/store"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// rateRe matches what fits the NUMERIC(20,10) rate column.
+var rateRe = regexp.MustCompile(`^[0-9]{1,10}(\.[0-9]{1,10})?$`)
+
+type CurrenciesHandler struct {
+	currencies *store.CurrencyStore
+	validate   *validator.Validate
+}
+
+func NewCurrenciesHandler(currencies *store.CurrencyStore, validate *validator.Validate) *CurrenciesHandler {
+	return &CurrenciesHandler{currencies: currencies, validate: validate}
+}
+
+func (h *CurrenciesHandler) ListRates(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	base := strings.ToUpper(strings.TrimSpace(q.Get("base")))
+	quote := strings.ToUpper(strings.TrimSpace(q.Get("quote")))
+
+	items, err := h.currencies.ListRates(r.Context(), base, quote)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch exchange rates", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, items, map[string]any{"count": len(items)})
+}
+
+func (h *CurrenciesHandler) CreateRate(w http.ResponseWriter, r *http.Request) {
+	cur, ok := middleware.CurrentUserFromContext(r.Context())
+	if !ok {
+		response.WriteError(w, http.StatusUnauthorized, "unauthorized", nil)
+		return
+	}
+
+	var req model.ExchangeRateCreateRequest
+	if err := response.DecodeJSON(w, r, &req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+
+	req.BaseCurrency = strings.ToUpper(strings.TrimSpace(req.BaseCurrency))
+	req.QuoteCurrency = strings.ToUpper(strings.TrimSpace(req.QuoteCurrency))
+	req.Rate = strings.TrimSpace(req.Rate)
+
+	if err := h.validate.Struct(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return
+	}
+	for _, c := range []string{req.BaseCurrency, req.QuoteCurrency} {
+		if _, ok := money.Exponent(c); !ok {
+			response.WriteError(w, http.StatusBadRequest, "unsupported currency", c)
+			return
+		}
+	}
+	if !rateRe.MatchString(req.Rate) || strings.Trim(req.Rate, "0.") == "" {
+		response.WriteError(w, http.StatusBadRequest, "validation error", "rate must be a positive decimal with at most 10 decimal places")
+		return
+	}
+
+	effectiveFrom := time.Now()
+	if req.EffectiveFrom != nil {
+		effectiveFrom = *req.EffectiveFrom
+	}
+
+	created, err := h.currencies.CreateRate(r.Context(), req.BaseCurrency, req.QuoteCurrency, req.Rate, effectiveFrom, cur.ID)
+	if err != nil {
+		if store.IsUniqueViolation(err) {
+			response.WriteError(w, http.StatusConflict, "a rate for this pair already takes effect at that time", nil)
+			return
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to create exchange rate", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusCreated, created, nil)
+}
+
+func (h *CurrenciesHandler) DeleteRate(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid exchange rate id", nil)
+		return
+	}
+
+	ok, err := h.currencies.DeleteRate(r.Context(), id)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to delete exchange rate", nil)
+		return
+	}
+	if !ok {
+		response.WriteError(w, http.StatusNotFound, "exchange rate not found", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, map[string]string{"status": "deleted"}, nil)
+}
+
+func (h *CurrenciesHandler) ListRoundingRules(w http.ResponseWriter, r *http.Request) {
+	items, err := h.currencies.ListRoundingRules(r.Context())
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch rounding rules", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, items, map[string]any{"count": len(items)})
+}
+
+func (h *CurrenciesHandler) SetRoundingRule(w http.ResponseWriter, r *http.Request) {
+	currency, ok := parseCurrency(chi.URLParam(r, "currency"))
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "unsupported currency", nil)
+		return
+	}
+
+	var req model.RoundingRuleRequest
+	if err := response.DecodeJSON(w, r, &req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return
+	}
+
+	increment, err := money.Parse(req.Increment, currency)
+	if err != nil || increment.Amount <= 0 {
+		response.WriteError(w, http.StatusBadRequest, "validation error", "increment must be a positive amount in "+currency)
+		return
+	}
+
+	rule, err := h.currencies.SetRoundingRule(r.Context(), increment, req.Mode)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to save rounding rule", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, rule, nil)
+}
+
+func (h *CurrenciesHandler) DeleteRoundingRule(w http.ResponseWriter, r *http.Request) {
+	currency, ok := parseCurrency(chi.URLParam(r, "currency"))
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "unsupported currency", nil)
+		return
+	}
+
+	ok, err := h.currencies.DeleteRoundingRule(r.Context(), currency)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to delete rounding rule", nil)
+		return
+	}
+	if !ok {
+		response.WriteError(w, http.StatusNotFound, "rounding rule not found", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, map[string]string{"status": "deleted"}, nil)
+}
+
+func parseCurrency(s string) (string, bool) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	_, ok := money.Exponent(s)
+	return s, ok
+}
@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"mini-product-catalog/internal/auth"
+	"mini-product-catalog/internal/response"
+	"net/http"
+)
+
+type JWKSHandler struct {
+	keys *auth.KeySet
+}
+
+func NewJWKSHandler(keys *auth.KeySet) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+// JWKS is served as a bare JWK Set document rather than in the usual data
+// envelope, since that is what JWT libraries expect to fetch.
+func (h *JWKSHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	response.WriteJSON(w, http.StatusOK, h.keys.JWKS())
+}
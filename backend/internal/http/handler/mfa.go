@@ -0,0 +1,272 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"mini-product-catalog/internal/auth"
+	"mini-product-catalog/internal/middleware"
+	"mini-product-catalog/internal/model"
+	"mini-product-catalog/internal/response"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const recoveryCodeCount = 10
+
+func (h *AuthHandler) SetupMFA(w http.ResponseWriter, r *http.Request) {
+	cur, ok := middleware.CurrentUserFromContext(r.Context())
+	if !ok {
+		response.WriteError(w, http.StatusUnauthorized, "unauthorized", nil)
+		return
+	}
+
+	u, err := h.users.GetByID(r.Context(), cur.ID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.WriteError(w, http.StatusUnauthorized, "user not found", nil)
+			return
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to set up two-factor authentication", nil)
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to set up two-factor authentication", nil)
+		return
+	}
+
+	if err := h.mfa.SetPendingSecret(r.Context(), u.ID, secret); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.WriteError(w, http.StatusConflict, "two-factor authentication is already enabled", nil)
+			return
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to set up two-factor authentication", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, map[string]string{
+		"secret":      secret,
+		"otpauth_url": auth.TOTPURI(h.cfg.MFAIssuer, u.Email, secret),
+	}, nil)
+}
+
+func (h *AuthHandler) ConfirmMFA(w http.ResponseWriter, r *http.Request) {
+	cur, ok := middleware.CurrentUserFromContext(r.Context())
+	if !ok {
+		response.WriteError(w, http.StatusUnauthorized, "unauthorized", nil)
+		return
+	}
+
+	var req model.MFACodeRequest
+	if err := response.DecodeJSON(w, r, &req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return
+	}
+
+	st, err := h.mfa.GetTOTP(r.Context(), cur.ID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.WriteError(w, http.StatusUnauthorized, "user not found", nil)
+			return
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to confirm two-factor authentication", nil)
+		return
+	}
+	if st.EnabledAt != nil {
+		response.WriteError(w, http.StatusConflict, "two-factor authentication is already enabled", nil)
+		return
+	}
+	if st.Secret == "" {
+		response.WriteError(w, http.StatusBadRequest, "call /me/mfa/setup first", nil)
+		return
+	}
+
+	step, ok := auth.ValidateTOTP(st.Secret, req.Code, time.Now())
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid code", nil)
+		return
+	}
+
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to confirm two-factor authentication", nil)
+		return
+	}
+	hashes := make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = auth.HashToken(c)
+	}
+
+	if err := h.mfa.Enable(r.Context(), cur.ID, step, hashes); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.WriteError(w, http.StatusConflict, "two-factor authentication is already enabled", nil)
+			return
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to confirm two-factor authentication", nil)
+		return
+	}
+
+	// Recovery codes are only ever shown here; the database keeps hashes.
+	response.WriteData(w, http.StatusOK, map[string]any{
+		"enabled":        true,
+		"recovery_codes": codes,
+	}, nil)
+}
+
+func (h *AuthHandler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	cur, ok := middleware.CurrentUserFromContext(r.Context())
+	if !ok {
+		response.WriteError(w, http.StatusUnauthorized, "unauthorized", nil)
+		return
+	}
+
+	if h.cfg.MFARequiredForStaff {
+		staff, err := h.roles.IsStaff(r.Context(), cur.Role)
+		if err != nil {
+			response.WriteError(w, http.StatusInternalServerError, "failed to resolve permissions", nil)
+			return
+		}
+		if staff {
+			response.WriteError(w, http.StatusForbidden, "two-factor authentication is required for your role", nil)
+			return
+		}
+	}
+
+	var req model.MFADisableRequest
+	if err := response.DecodeJSON(w, r, &req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return
+	}
+
+	ok, err := h.checkSecondFactor(r.Context(), cur.ID, req.Code, req.RecoveryCode)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to disable two-factor authentication", nil)
+		return
+	}
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "invalid code", nil)
+		return
+	}
+
+	if err := h.mfa.Disable(r.Context(), cur.ID); err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to disable two-factor authentication", nil)
+		return
+	}
+	h.users.ForgetStatus(cur.ID)
+
+	// Disabling the second factor signs out every other session; the caller
+	// gets a fresh one that no longer claims MFA.
+	if err := h.refreshTokens.RevokeAllForUser(r.Context(), cur.ID); err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to revoke sessions", nil)
+		return
+	}
+	u, ok := h.loadSelf(w, r, cur.ID)
+	if !ok {
+		return
+	}
+	session, err := h.startSession(r.Context(), u, false)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to generate token", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, map[string]bool{"enabled": false}, map[string]any{"session": session})
+}
+
+func (h *AuthHandler) VerifyMFA(w http.ResponseWriter, r *http.Request) {
+	var req model.MFAVerifyRequest
+	if err := response.DecodeJSON(w, r, &req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return
+	}
+
+	userID, err := auth.ParseMFAChallenge(h.cfg.Keys, req.MFAToken)
+	if err != nil {
+		response.WriteError(w, http.StatusUnauthorized, "invalid or expired mfa token", nil)
+		return
+	}
+
+	mfaKey := "mfa:" + userID.String()
+	if h.checkLocked(w, r, mfaKey) {
+		return
+	}
+
+	ok, err := h.checkSecondFactor(r.Context(), userID, req.Code, req.RecoveryCode)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to verify code", nil)
+		return
+	}
+	if !ok {
+		h.recordFailure(r.Context(), mfaKey, h.cfg.AccountLockout)
+		response.WriteError(w, http.StatusUnauthorized, "invalid code", nil)
+		return
+	}
+
+	if err := h.throttles.Reset(r.Context(), mfaKey); err != nil {
+		slog.Error("failed to reset login throttle", "key", mfaKey, "err", err)
+	}
+
+	u, err := h.users.GetByID(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.WriteError(w, http.StatusUnauthorized, "invalid or expired mfa token", nil)
+			return
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to login", nil)
+		return
+	}
+	if u.DisabledAt != nil {
+		response.WriteError(w, http.StatusForbidden, "account disabled", nil)
+		return
+	}
+
+	resp, err := h.startSession(r.Context(), u, true)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to generate token", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, resp, nil)
+}
+
+// checkSecondFactor accepts either a current TOTP code or an unused recovery
+// code. Each TOTP step and each recovery code can be used only once.
+func (h *AuthHandler) checkSecondFactor(ctx context.Context, userID uuid.UUID, code, recoveryCode string) (bool, error) {
+	st, err := h.mfa.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	if st.EnabledAt == nil {
+		return false, nil
+	}
+
+	if code != "" {
+		step, ok := auth.ValidateTOTP(st.Secret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+		return h.mfa.UseStep(ctx, userID, step)
+	}
+
+	return h.mfa.UseRecoveryCode(ctx, userID, auth.HashToken(auth.NormalizeRecoveryCode(recoveryCode)))
+}
@@ -0,0 +1,283 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"mini-product-catalog/internal/auth"
+	"mini-product-catalog/internal/model"
+	"mini-product-catalog/internal/oidc"
+	"mini-product-catalog/internal/response"
+	"mini-product-catalog/internal/store"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	oidcStateTTL     = 10 * time.Minute
+	oidcLoginCodeTTL = time.Minute
+)
+
+var (
+	errOIDCNoEmail       = errors.New("identity provider did not return an email address")
+	errOIDCSignupClosed  = errors.New("no account is linked to this identity")
+	errOIDCEmailConflict = errors.New("an account with this email already exists, sign in with your password first")
+)
+
+func (h *AuthHandler) ListOIDCProviders(w http.ResponseWriter, r *http.Request) {
+	out := []map[string]string{}
+	for name, p := range h.cfg.OIDCProviders {
+		out = append(out, map[string]string{
+			"name":         name,
+			"display_name": p.DisplayName(),
+			"start_url":    "/auth/oidc/" + name + "/start",
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i]["name"] < out[j]["name"] })
+
+	response.WriteData(w, http.StatusOK, out, nil)
+}
+
+func (h *AuthHandler) StartOIDC(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "provider")
+	p, ok := h.cfg.OIDCProviders[name]
+	if !ok {
+		response.WriteError(w, http.StatusNotFound, "unknown identity provider", nil)
+		return
+	}
+
+	state, err1 := auth.NewOpaqueToken()
+	nonce, err2 := auth.NewOpaqueToken()
+	verifier, err3 := auth.NewOpaqueToken()
+	if err := errors.Join(err1, err2, err3); err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to start login", nil)
+		return
+	}
+
+	redirectTo := r.URL.Query().Get("redirect")
+	if !isLocalPath(redirectTo) {
+		redirectTo = "/"
+	}
+
+	authURL, err := p.AuthCodeURL(r.Context(), state, nonce, verifier)
+	if err != nil {
+		slog.Error("oidc discovery failed", "provider", name, "err", err)
+		response.WriteError(w, http.StatusBadGateway, "identity provider unavailable", nil)
+		return
+	}
+
+	if err := h.identities.CreateState(r.Context(), auth.HashToken(state), model.OIDCState{
+		Provider:     name,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		RedirectTo:   redirectTo,
+	}, time.Now().Add(oidcStateTTL)); err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to start login", nil)
+		return
+	}
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallback is reached by the browser, so every outcome is a redirect to
+// the frontend. On success it carries a short-lived one-time code that the
+// frontend trades for tokens at ExchangeOIDC, keeping tokens out of URLs.
+func (h *AuthHandler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "provider")
+	q := r.URL.Query()
+
+	fail := func(msg string) {
+		http.Redirect(w, r, h.appURL("/login", url.Values{"error": {msg}}), http.StatusFound)
+	}
+
+	p, ok := h.cfg.OIDCProviders[name]
+	if !ok {
+		fail("unknown identity provider")
+		return
+	}
+
+	st, err := h.identities.ConsumeState(r.Context(), auth.HashToken(q.Get("state")))
+	if err != nil || st.Provider != name {
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			slog.Error("failed to load oidc state", "err", err)
+		}
+		fail("login expired, please try again")
+		return
+	}
+
+	if e := q.Get("error"); e != "" {
+		fail("identity provider error: " + e)
+		return
+	}
+
+	id, err := p.Exchange(r.Context(), q.Get("code"), st.CodeVerifier, st.Nonce)
+	if err != nil {
+		slog.Error("oidc code exchange failed", "provider", name, "err", err)
+		fail("sign-in with the identity provider failed")
+		return
+	}
+
+	u, err := h.resolveOIDCUser(r.Context(), p, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, errOIDCNoEmail), errors.Is(err, errOIDCSignupClosed), errors.Is(err, errOIDCEmailConflict):
+			fail(err.Error())
+		default:
+			slog.Error("oidc user provisioning failed", "provider", name, "err", err)
+			fail("sign-in with the identity provider failed")
+		}
+		return
+	}
+
+	code, err := auth.NewOpaqueToken()
+	if err != nil {
+		fail("sign-in with the identity provider failed")
+		return
+	}
+	if _, err := h.userTokens.Create(r.Context(), u.ID, model.TokenPurposeOIDCLogin, auth.HashToken(code), time.Now().Add(oidcLoginCodeTTL)); err != nil {
+		slog.Error("failed to store oidc login code", "err", err)
+		fail("sign-in with the identity provider failed")
+		return
+	}
+
+	http.Redirect(w, r, h.appURL("/oidc/callback", url.Values{"code": {code}, "redirect": {st.RedirectTo}}), http.StatusFound)
+}
+
+func (h *AuthHandler) ExchangeOIDC(w http.ResponseWriter, r *http.Request) {
+	var req model.OIDCExchangeRequest
+	if err := response.DecodeJSON(w, r, &req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return
+	}
+
+	t, err := h.userTokens.Consume(r.Context(), model.TokenPurposeOIDCLogin, auth.HashToken(req.Code))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.WriteError(w, http.StatusUnauthorized, "invalid or expired login code", nil)
+			return
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to login", nil)
+		return
+	}
+
+	u, err := h.users.GetByID(r.Context(), t.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.WriteError(w, http.StatusUnauthorized, "invalid or expired login code", nil)
+			return
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to login", nil)
+		return
+	}
+
+	h.completeLogin(w, r, u)
+}
+
+// resolveOIDCUser finds the user for an external identity: an existing link,
+// else an account with the same verified email (which gets linked), else a
+// newly provisioned account. Group mappings are applied on every login.
+func (h *AuthHandler) resolveOIDCUser(ctx context.Context, p *oidc.Provider, id oidc.Identity) (model.User, error) {
+	mappedRole, mapped := p.MapRole(id.Groups)
+
+	var u model.User
+	ident, err := h.identities.GetIdentity(ctx, p.Name(), id.Subject)
+	switch {
+	case err == nil:
+		u, err = h.users.GetByID(ctx, ident.UserID)
+		if err != nil {
+			return model.User{}, err
+		}
+		if err := h.identities.TouchIdentity(ctx, ident.ID, id.Email); err != nil {
+			slog.Error("failed to update identity", "identity_id", ident.ID, "err", err)
+		}
+
+	case errors.Is(err, pgx.ErrNoRows):
+		if id.Email == "" {
+			return model.User{}, errOIDCNoEmail
+		}
+
+		u, err = h.users.GetByEmail(ctx, id.Email)
+		switch {
+		case err == nil:
+			// Only trust the provider's claim to an existing address if it
+			// has verified it.
+			if !id.EmailVerified {
+				return model.User{}, errOIDCEmailConflict
+			}
+		case errors.Is(err, pgx.ErrNoRows):
+			if !p.AllowSignup() {
+				return model.User{}, errOIDCSignupClosed
+			}
+			u, err = h.provisionOIDCUser(ctx, p, id, mappedRole, mapped)
+			if err != nil {
+				return model.User{}, err
+			}
+		default:
+			return model.User{}, err
+		}
+
+		if _, err := h.identities.LinkIdentity(ctx, u.ID, p.Name(), id.Subject, id.Email); err != nil {
+			return model.User{}, err
+		}
+
+	default:
+		return model.User{}, err
+	}
+
+	if mapped && u.Role != mappedRole {
+		updated, err := h.users.Update(ctx, u.ID, store.UserUpdate{Role: &mappedRole})
+		if err != nil {
+			slog.Error("failed to apply oidc role mapping", "user_id", u.ID, "role", mappedRole, "err", err)
+		} else {
+			u = updated
+		}
+	}
+
+	return u, nil
+}
+
+func (h *AuthHandler) provisionOIDCUser(ctx context.Context, p *oidc.Provider, id oidc.Identity, mappedRole string, mapped bool) (model.User, error) {
+	role := p.DefaultRole()
+	if mapped {
+		role = mappedRole
+	}
+
+	name := strings.TrimSpace(id.Name)
+	if name == "" {
+		name, _, _ = strings.Cut(id.Email, "@")
+	}
+
+	// Federated accounts have no local password; an empty hash never matches.
+	u, err := h.users.Create(ctx, name, id.Email, "", role)
+	if err != nil {
+		if store.IsUniqueViolation(err) {
+			return model.User{}, errOIDCEmailConflict
+		}
+		return model.User{}, err
+	}
+
+	if id.EmailVerified {
+		return h.users.MarkEmailVerified(ctx, u.ID)
+	}
+	return u, nil
+}
+
+func (h *AuthHandler) appURL(path string, q url.Values) string {
+	return strings.TrimRight(h.cfg.AppBaseURL, "/") + path + "?" + q.Encode()
+}
+
+// isLocalPath accepts only same-origin absolute paths, so the post-login
+// redirect can't be used to send users to another site.
+func isLocalPath(p string) bool {
+	return strings.HasPrefix(p, "/") && !strings.HasPrefix(p, "//") && !strings.HasPrefix(p, "/\\")
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"mini-product-catalog/internal/auth"
+	"mini-product-catalog/internal/mail"
+	"mini-product-catalog/internal/model"
+	"mini-product-catalog/internal/response"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// accountMailTimeout bounds the work done after answering account recovery
+// requests.
+const accountMailTimeout = 30 * time.Second
+
+// afterResponse runs fn once the handler has answered, detached from the
+// request's cancellation. Account recovery endpoints use it so that how long
+// they take doesn't reveal whether an account exists.
+func afterResponse(r *http.Request, what string, fn func(ctx context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), accountMailTimeout)
+	go func() {
+		defer cancel()
+		if err := fn(ctx); err != nil {
+			slog.Error("failed to "+what, "err", err)
+		}
+	}()
+}
+
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req model.ForgotPasswordRequest
+	if err := response.DecodeJSON(w, r, &req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+
+	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
+
+	if err := h.validate.Struct(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return
+	}
+
+	// The answer is the same whether or not the account exists, and is sent
+	// before looking it up, so neither its content nor its timing reveals
+	// registered emails.
+	response.WriteData(w, http.StatusAccepted, map[string]string{"status": "if the account exists, a reset link has been sent"}, nil)
+
+	afterResponse(r, "send password reset email", func(ctx context.Context) error {
+		u, err := h.users.GetByEmail(ctx, req.Email)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		token, err := auth.NewOpaqueToken()
+		if err != nil {
+			return err
+		}
+		if _, err := h.userTokens.Create(ctx, u.ID, model.TokenPurposePasswordReset, auth.HashToken(token), time.Now().Add(h.cfg.PasswordResetTTL)); err != nil {
+			return err
+		}
+
+		link := strings.TrimRight(h.cfg.AppBaseURL, "/") + "/reset-password?token=" + url.QueryEscape(token)
+		return h.mailer.Send(ctx, mail.PasswordResetMessage(u.Email, u.Name, link, h.cfg.PasswordResetTTL))
+	})
+}
+
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req model.ResetPasswordRequest
+	if err := response.DecodeJSON(w, r, &req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to hash password", nil)
+		return
+	}
+
+	t, err := h.userTokens.Consume(r.Context(), model.TokenPurposePasswordReset, auth.HashToken(req.Token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.WriteError(w, http.StatusBadRequest, "invalid or expired reset token", nil)
+			return
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to reset password", nil)
+		return
+	}
+
+	if err := h.users.UpdatePassword(r.Context(), t.UserID, string(hash)); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.WriteError(w, http.StatusBadRequest, "invalid or expired reset token", nil)
+			return
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to reset password", nil)
+		return
+	}
+
+	if err := h.refreshTokens.RevokeAllForUser(r.Context(), t.UserID); err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to revoke sessions", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, map[string]string{"status": "password updated"}, nil)
+}
@@ -1,12 +1,21 @@
 package handler
 
 import (
+	"cmp"
 	"errors"
+	"fmt"
+	"log/slog"
+	"math"
 	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
+	"mini-product-catalog/internal/blob"
 	"mini-product-catalog/internal/model"
+	"mini-product-catalog/internal/money"
 	"mini-product-catalog/internal/response"
 	"mini-product-catalog/internal/store"
 
@@ -17,13 +26,53 @@ import (
 )
 
 type ProductsHandler struct {
-	products   *store.ProductStore
-	categories *store.CategoryStore
-	validate   *validator.Validate
+	products     *store.ProductStore
+	variants     *store.VariantStore
+	images       *store.ImageStore
+	categories   *store.CategoryStore
+	currencies   *store.CurrencyStore
+	blobs        blob.BlobStore
+	validate     *validator.Validate
+	priceBuckets []money.Money
+
+	maxImageBytes int64
 }
 
-func NewProductsHandler(products *store.ProductStore, categories *store.CategoryStore, validate *validator.Validate) *ProductsHandler {
-	return &ProductsHandler{products: products, categories: categories, validate: validate}
+type ProductsConfig struct {
+	// PriceFacetBuckets are the default comma separated price bucket
+	// boundaries for facets=price, overridable per request with price_buckets.
+	PriceFacetBuckets string
+	// MaxImageBytes caps the size of an uploaded image file.
+	MaxImageBytes int64
+}
+
+const (
+	maxPriceFacetBuckets = 20
+	maxAttributeFilters  = 10
+	maxAttributeValues   = 20
+)
+
+var skuRe = regexp.MustCompile(`^[A-Z0-9][A-Z0-9._-]*$`)
+
+func NewProductsHandler(products *store.ProductStore, variants *store.VariantStore, images *store.ImageStore, categories *store.CategoryStore, currencies *store.CurrencyStore, blobs blob.BlobStore, validate *validator.Validate, cfg ProductsConfig) *ProductsHandler {
+	h := &ProductsHandler{
+		products:      products,
+		variants:      variants,
+		images:        images,
+		categories:    categories,
+		currencies:    currencies,
+		blobs:         blobs,
+		validate:      validate,
+		maxImageBytes: cfg.MaxImageBytes,
+	}
+	if cfg.PriceFacetBuckets != "" {
+		b, err := parsePriceBuckets(cfg.PriceFacetBuckets, money.DefaultCurrency())
+		if err != nil {
+			slog.Error("ignoring invalid price facet buckets", "err", err)
+		}
+		h.priceBuckets = b
+	}
+	return h
 }
 
 func (h *ProductsHandler) List(w http.ResponseWriter, r *http.Request) {
@@ -32,6 +81,11 @@ func (h *ProductsHandler) List(w http.ResponseWriter, r *http.Request) {
 	page := parseInt(q.Get("page"), 1)
 	limit := parseInt(q.Get("limit"), 10)
 
+	currency, ok := requestedCurrency(w, r)
+	if !ok {
+		return
+	}
+
 	var categoryID *uuid.UUID
 	if v := strings.TrimSpace(q.Get("category_id")); v != "" {
 		id, err := uuid.Parse(v)
@@ -42,50 +96,150 @@ func (h *ProductsHandler) List(w http.ResponseWriter, r *http.Request) {
 		categoryID = &id
 	}
 
-	var minPrice *float64
+	// Price filters, sorting and facets are in the currency prices are shown
+	// in, or the default currency when none was requested.
+	priceCurrency := currency
+	if priceCurrency == "" {
+		priceCurrency = money.DefaultCurrency()
+	}
+
+	var minPrice *money.Money
 	if v := strings.TrimSpace(q.Get("min_price")); v != "" {
-		f, err := strconv.ParseFloat(v, 64)
+		m, err := money.Parse(v, priceCurrency)
 		if err != nil {
-			response.WriteError(w, http.StatusBadRequest, "invalid min_price", nil)
+			response.WriteError(w, http.StatusBadRequest, "invalid min_price", err.Error())
 			return
 		}
-		minPrice = &f
+		minPrice = &m
 	}
 
-	var maxPrice *float64
+	var maxPrice *money.Money
 	if v := strings.TrimSpace(q.Get("max_price")); v != "" {
-		f, err := strconv.ParseFloat(v, 64)
+		m, err := money.Parse(v, priceCurrency)
 		if err != nil {
-			response.WriteError(w, http.StatusBadRequest, "invalid max_price", nil)
+			response.WriteError(w, http.StatusBadRequest, "invalid max_price", err.Error())
 			return
 		}
-		maxPrice = &f
+		maxPrice = &m
+	}
+
+	var inStock *bool
+	if v := strings.TrimSpace(q.Get("in_stock")); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			response.WriteError(w, http.StatusBadRequest, "invalid in_stock", nil)
+			return
+		}
+		inStock = &b
+	}
+
+	attributes, err := parseAttributeFilters(q)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid attribute filter", err.Error())
+		return
 	}
 
 	opt := store.ProductListOptions{
-		Page:       page,
-		Limit:      limit,
-		CategoryID: categoryID,
-		MinPrice:   minPrice,
-		MaxPrice:   maxPrice,
-		Q:          q.Get("q"),
-		Sort:       q.Get("sort"),
-		Order:      q.Get("order"),
+		Page:                 page,
+		Limit:                limit,
+		CategoryID:           categoryID,
+		IncludeSubcategories: q.Get("include_subcategories") == "true",
+		IncludeHidden:        includeHidden(r),
+		PriceCurrency:        priceCurrency,
+		MinPrice:             minPrice,
+		MaxPrice:             maxPrice,
+		Q:                    q.Get("q"),
+		InStock:              inStock,
+		Attributes:           attributes,
+		Highlight:            q.Get("highlight") == "true",
+		Sort:                 q.Get("sort"),
+		Order:                q.Get("order"),
+		SkipTotal:            q.Get("include_total") == "false",
+	}
+
+	if v := strings.TrimSpace(q.Get("cursor")); v != "" {
+		c, err := store.DecodeProductCursor(v)
+		if err != nil {
+			response.WriteError(w, http.StatusBadRequest, "invalid cursor", nil)
+			return
+		}
+		opt.Cursor = &c
+	}
+
+	var facets []string
+	for _, f := range strings.Split(q.Get("facets"), ",") {
+		switch f = strings.TrimSpace(f); f {
+		case "":
+		case store.FacetCategory, store.FacetPrice:
+			facets = append(facets, f)
+		default:
+			response.WriteError(w, http.StatusBadRequest, "invalid facets", map[string]string{
+				"allowed": store.FacetCategory + "," + store.FacetPrice,
+			})
+			return
+		}
+	}
+
+	priceBuckets := h.priceBuckets
+	if v := strings.TrimSpace(q.Get("price_buckets")); v != "" {
+		b, err := parsePriceBuckets(v, priceCurrency)
+		if err != nil {
+			response.WriteError(w, http.StatusBadRequest, "invalid price_buckets", err.Error())
+			return
+		}
+		priceBuckets = b
+	} else if slices.Contains(facets, store.FacetPrice) && priceCurrency != money.DefaultCurrency() {
+		b, err := h.convertPriceBuckets(r.Context(), priceBuckets, priceCurrency)
+		if err != nil {
+			writeLocalizeError(w, err)
+			return
+		}
+		priceBuckets = b
 	}
 
-	items, total, err := h.products.List(r.Context(), opt)
+	res, err := h.products.List(r.Context(), opt)
 	if err != nil {
+		if errors.Is(err, store.ErrInvalidCursor) {
+			response.WriteError(w, http.StatusBadRequest, "invalid cursor", "cursors only work with the sort, order and currency they were issued for")
+			return
+		}
 		response.WriteError(w, http.StatusInternalServerError, "failed to fetch products", nil)
 		return
 	}
+	if err := h.localize(r.Context(), res.Items, currency); err != nil {
+		writeLocalizeError(w, err)
+		return
+	}
+	if err := h.attachPrimaryImages(r.Context(), res.Items); err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch images", nil)
+		return
+	}
 
+	var nextCursor any
+	if res.NextCursor != "" {
+		nextCursor = res.NextCursor
+	}
 	meta := map[string]any{
-		"page":  opt.Page,
-		"limit": opt.Limit,
-		"total": total,
+		"limit":       opt.Limit,
+		"next_cursor": nextCursor,
+	}
+	if opt.Cursor == nil {
+		meta["page"] = opt.Page
+	}
+	if res.Total != nil {
+		meta["total"] = *res.Total
 	}
 
-	response.WriteData(w, http.StatusOK, items, meta)
+	if len(facets) > 0 {
+		f, err := h.products.Facets(r.Context(), opt, facets, priceBuckets)
+		if err != nil {
+			response.WriteError(w, http.StatusInternalServerError, "failed to fetch facets", nil)
+			return
+		}
+		meta["facets"] = f
+	}
+
+	response.WriteData(w, http.StatusOK, res.Items, meta)
 }
 
 func (h *ProductsHandler) Get(w http.ResponseWriter, r *http.Request) {
@@ -97,6 +251,39 @@ func (h *ProductsHandler) Get(w http.ResponseWriter, r *http.Request) {
 	}
 
 	p, err := h.products.GetByID(r.Context(), id)
+	h.writeProduct(w, r, p, err, nil)
+}
+
+func (h *ProductsHandler) GetBySKU(w http.ResponseWriter, r *http.Request) {
+	sku := strings.ToUpper(strings.TrimSpace(chi.URLParam(r, "sku")))
+
+	p, err := h.products.GetBySKU(r.Context(), sku)
+	h.writeProduct(w, r, p, err, nil)
+}
+
+// GetBySlug also resolves slugs a product had before it was renamed. Those
+// responses carry the current slug in meta.redirect so clients can update
+// their URLs.
+func (h *ProductsHandler) GetBySlug(w http.ResponseWriter, r *http.Request) {
+	slug := strings.ToLower(chi.URLParam(r, "slug"))
+
+	p, moved, err := h.products.GetBySlug(r.Context(), slug)
+	var meta map[string]any
+	if err == nil && moved {
+		meta = map[string]any{
+			"redirect": map[string]string{
+				"slug": p.Slug,
+				"path": "/products/by-slug/" + p.Slug,
+			},
+		}
+	}
+	h.writeProduct(w, r, p, err, meta)
+}
+
+// writeProduct finishes a single-product lookup: it nests the options and
+// variants and converts prices when the request asks for another currency.
+// Products in hidden categories are not found unless includeHidden.
+func (h *ProductsHandler) writeProduct(w http.ResponseWriter, r *http.Request, p model.Product, err error, meta map[string]any) {
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			response.WriteError(w, http.StatusNotFound, "product not found", nil)
@@ -105,8 +292,34 @@ func (h *ProductsHandler) Get(w http.ResponseWriter, r *http.Request) {
 		response.WriteError(w, http.StatusInternalServerError, "failed to fetch product", nil)
 		return
 	}
+	var shown bool
+	if p.Breadcrumbs, shown, err = h.categories.Breadcrumbs(r.Context(), p.CategoryID); err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch breadcrumbs", nil)
+		return
+	}
+	if !shown && !includeHidden(r) {
+		response.WriteError(w, http.StatusNotFound, "product not found", nil)
+		return
+	}
+	if err := h.loadVariants(r, &p); err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch variants", nil)
+		return
+	}
+	if err := h.loadImages(r.Context(), &p); err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch images", nil)
+		return
+	}
+	currency, ok := requestedCurrency(w, r)
+	if !ok {
+		return
+	}
+	items := []model.Product{p}
+	if err := h.localize(r.Context(), items, currency); err != nil {
+		writeLocalizeError(w, err)
+		return
+	}
 
-	response.WriteData(w, http.StatusOK, p, nil)
+	response.WriteData(w, http.StatusOK, items[0], meta)
 }
 
 func (h *ProductsHandler) Create(w http.ResponseWriter, r *http.Request) {
@@ -115,10 +328,17 @@ func (h *ProductsHandler) Create(w http.ResponseWriter, r *http.Request) {
 		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
 		return
 	}
+
+	req.SKU = strings.ToUpper(strings.TrimSpace(req.SKU))
+
 	if err := h.validate.Struct(req); err != nil {
 		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
 		return
 	}
+	if !skuRe.MatchString(req.SKU) {
+		response.WriteError(w, http.StatusBadRequest, "validation error", "sku may only contain letters, digits, '.', '_' and '-'")
+		return
+	}
 
 	catID, _ := uuid.Parse(req.CategoryID)
 
@@ -132,8 +352,23 @@ func (h *ProductsHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	created, err := h.products.Create(r.Context(), catID, req.Name, req.Description, req.Price)
+	schema, err := h.categories.ListAttributes(r.Context(), catID)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to validate attributes", nil)
+		return
+	}
+	attributes, err := checkAttributes(schema, req.Attributes)
 	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return
+	}
+
+	created, err := h.products.Create(r.Context(), catID, req.SKU, req.Name, req.Description, req.Price, attributes)
+	if err != nil {
+		if errors.Is(err, store.ErrSKUTaken) || store.IsUniqueViolation(err) {
+			response.WriteError(w, http.StatusConflict, "sku already exists", nil)
+			return
+		}
 		response.WriteError(w, http.StatusInternalServerError, "failed to create product", nil)
 		return
 	}
@@ -154,10 +389,17 @@ func (h *ProductsHandler) Update(w http.ResponseWriter, r *http.Request) {
 		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
 		return
 	}
+
+	req.SKU = strings.ToUpper(strings.TrimSpace(req.SKU))
+
 	if err := h.validate.Struct(req); err != nil {
 		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
 		return
 	}
+	if !skuRe.MatchString(req.SKU) {
+		response.WriteError(w, http.StatusBadRequest, "validation error", "sku may only contain letters, digits, '.', '_' and '-'")
+		return
+	}
 
 	catID, _ := uuid.Parse(req.CategoryID)
 
@@ -171,8 +413,27 @@ func (h *ProductsHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updated, err := h.products.Update(r.Context(), id, catID, req.Name, req.Description, req.Price)
+	schema, err := h.categories.ListAttributes(r.Context(), catID)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to validate attributes", nil)
+		return
+	}
+	attributes, err := checkAttributes(schema, req.Attributes)
 	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return
+	}
+
+	updated, err := h.products.Update(r.Context(), id, catID, req.SKU, req.Name, req.Description, req.Price, attributes)
+	if err != nil {
+		if errors.Is(err, store.ErrSKUTaken) || store.IsUniqueViolation(err) {
+			response.WriteError(w, http.StatusConflict, "sku already exists", nil)
+			return
+		}
+		if errors.Is(err, store.ErrVariantCurrency) {
+			response.WriteError(w, http.StatusConflict, "the product's variants are priced in its current currency; remove them before changing currency", nil)
+			return
+		}
 		if errors.Is(err, pgx.ErrNoRows) {
 			response.WriteError(w, http.StatusNotFound, "product not found", nil)
 			return
@@ -192,15 +453,29 @@ func (h *ProductsHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Image rows go with the product; their files are removed afterwards.
+	images, err := h.images.List(r.Context(), id)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to delete product", nil)
+		return
+	}
+
 	deleted, err := h.products.Delete(r.Context(), id)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			response.WriteError(w, http.StatusNotFound, "product not found", nil)
 			return
 		}
+		if errors.Is(err, store.ErrStockReserved) {
+			response.WriteError(w, http.StatusConflict, "stock is reserved for this product or its variants; release it before deleting", nil)
+			return
+		}
 		response.WriteError(w, http.StatusInternalServerError, "failed to delete product", nil)
 		return
 	}
+	for _, img := range images {
+		h.deleteBlobs(r.Context(), imageKeys(img))
+	}
 
 	response.WriteData(w, http.StatusOK, deleted, nil)
 }
@@ -216,3 +491,77 @@ func parseInt(s string, def int) int {
 	}
 	return v
 }
+
+// parsePriceBuckets reads comma separated bucket boundaries in the default
+// currency, returning them sorted and without duplicates.
+func parsePriceBuckets(s, currency string) ([]money.Money, error) {
+	var out []money.Money
+	for _, p := range strings.Split(s, ",") {
+		m, err := money.Parse(p, currency)
+		if err != nil || m.Amount <= 0 {
+			return nil, fmt.Errorf("%q is not a positive amount", strings.TrimSpace(p))
+		}
+		out = append(out, m)
+	}
+	if len(out) > maxPriceFacetBuckets {
+		return nil, fmt.Errorf("at most %d boundaries are allowed", maxPriceFacetBuckets)
+	}
+
+	slices.SortFunc(out, func(a, b money.Money) int { return cmp.Compare(a.Amount, b.Amount) })
+	return slices.Compact(out), nil
+}
+
+// parseAttributeFilters reads attr.<key>=a,b (any of the values) and
+// attr.<key>_gt, _gte, _lt and _lte (numeric comparisons).
+func parseAttributeFilters(q url.Values) ([]store.AttributeFilter, error) {
+	var out []store.AttributeFilter
+	for name, vals := range q {
+		key, ok := strings.CutPrefix(name, "attr.")
+		if !ok {
+			continue
+		}
+		v := strings.TrimSpace(vals[len(vals)-1])
+
+		f := store.AttributeFilter{Key: key, Op: "eq"}
+		for _, op := range attributeFilterOps {
+			if k, ok := strings.CutSuffix(key, "_"+op); ok {
+				f.Key, f.Op = k, op
+				break
+			}
+		}
+		if !validAttributeKey(f.Key) {
+			return nil, fmt.Errorf("unknown attribute key in %s", name)
+		}
+
+		if f.Op == "eq" {
+			for _, s := range strings.Split(v, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					f.Values = append(f.Values, s)
+				}
+			}
+			if len(f.Values) == 0 {
+				return nil, fmt.Errorf("%s needs a value", name)
+			}
+			if len(f.Values) > maxAttributeValues {
+				return nil, fmt.Errorf("%s allows at most %d values", name, maxAttributeValues)
+			}
+		} else {
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil || math.IsInf(n, 0) || math.IsNaN(n) {
+				return nil, fmt.Errorf("%s must be a number", name)
+			}
+			f.Number = n
+		}
+
+		out = append(out, f)
+	}
+	if len(out) > maxAttributeFilters {
+		return nil, fmt.Errorf("at most %d attribute filters are allowed", maxAttributeFilters)
+	}
+
+	// Map order is random; keep the generated SQL stable.
+	slices.SortFunc(out, func(a, b store.AttributeFilter) int {
+		return cmp.Or(cmp.Compare(a.Key, b.Key), cmp.Compare(a.Op, b.Op))
+	})
+	return out, nil
+}
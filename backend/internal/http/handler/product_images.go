@@ -0,0 +1,357 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mini-product-catalog/internal/imaging"
+	"mini-product-catalog/internal/model"
+	"mini-product-catalog/internal/response"
+	"mini-product-catalog/internal/store"
+	"net/http"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// imageExtensions lists the accepted upload types, detected from the file
+// content rather than the client's Content-Type.
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// thumbnailSizes are the longest-side pixel sizes generated per upload.
+var thumbnailSizes = map[string]int{
+	"small":  200,
+	"medium": 600,
+}
+
+const maxAltTextLength = 300
+
+func (h *ProductsHandler) ListImages(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid product id", nil)
+		return
+	}
+
+	items, err := h.images.List(r.Context(), id)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch images", nil)
+		return
+	}
+	for i := range items {
+		h.imageURLs(&items[i])
+	}
+
+	response.WriteData(w, http.StatusOK, items, map[string]any{"count": len(items)})
+}
+
+// UploadImage takes a multipart form with the image in "file" and optional
+// "alt_text". The original and its thumbnails go to the blob store before
+// the database row is written, and are removed again if that fails.
+func (h *ProductsHandler) UploadImage(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid product id", nil)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxImageBytes+1<<20)
+	if err := r.ParseMultipartForm(8 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			response.WriteError(w, http.StatusRequestEntityTooLarge, "image is too large", map[string]int64{"max_bytes": h.maxImageBytes})
+			return
+		}
+		response.WriteError(w, http.StatusBadRequest, "invalid multipart form", nil)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	altText := strings.TrimSpace(r.FormValue("alt_text"))
+	if len(altText) > maxAltTextLength {
+		response.WriteError(w, http.StatusBadRequest, "validation error", fmt.Sprintf("alt_text must be at most %d characters", maxAltTextLength))
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "file is required", nil)
+		return
+	}
+	defer file.Close()
+	if header.Size > h.maxImageBytes {
+		response.WriteError(w, http.StatusRequestEntityTooLarge, "image is too large", map[string]int64{"max_bytes": h.maxImageBytes})
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, h.maxImageBytes+1))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "failed to read upload", nil)
+		return
+	}
+	if int64(len(data)) > h.maxImageBytes {
+		response.WriteError(w, http.StatusRequestEntityTooLarge, "image is too large", map[string]int64{"max_bytes": h.maxImageBytes})
+		return
+	}
+
+	contentType := mimetype.Detect(data).String()
+	ext, ok := imageExtensions[contentType]
+	if !ok {
+		response.WriteError(w, http.StatusUnsupportedMediaType, "unsupported image type", map[string]string{
+			"detected": contentType,
+			"allowed":  "image/jpeg, image/png, image/gif",
+		})
+		return
+	}
+
+	img, err := imaging.Decode(data)
+	if err != nil {
+		if errors.Is(err, imaging.ErrTooLarge) {
+			response.WriteError(w, http.StatusRequestEntityTooLarge, "image dimensions are too large", map[string]int{"max_pixels": imaging.MaxPixels})
+			return
+		}
+		response.WriteError(w, http.StatusBadRequest, "could not decode image", nil)
+		return
+	}
+
+	pi := model.ProductImage{
+		ID:            uuid.New(),
+		ProductID:     id,
+		AltText:       altText,
+		ContentType:   contentType,
+		Width:         img.Bounds().Dx(),
+		Height:        img.Bounds().Dy(),
+		SizeBytes:     int64(len(data)),
+		ThumbnailKeys: map[string]string{},
+	}
+	prefix := fmt.Sprintf("products/%s/%s/", id, pi.ID)
+
+	var written []string
+	put := func(key string, body []byte, ct string) error {
+		if err := h.blobs.Put(r.Context(), key, bytes.NewReader(body), ct); err != nil {
+			return err
+		}
+		written = append(written, key)
+		return nil
+	}
+
+	pi.OriginalKey = prefix + "original" + ext
+	err = put(pi.OriginalKey, data, contentType)
+	for name, size := range thumbnailSizes {
+		if err != nil {
+			break
+		}
+		var buf bytes.Buffer
+		var ct string
+		if ct, err = imaging.Encode(&buf, imaging.Thumbnail(img, size), contentType); err != nil {
+			break
+		}
+		key := prefix + name + imageExtensions[ct]
+		if err = put(key, buf.Bytes(), ct); err == nil {
+			pi.ThumbnailKeys[name] = key
+		}
+	}
+	if err != nil {
+		h.deleteBlobs(r.Context(), written)
+		response.WriteError(w, http.StatusInternalServerError, "failed to store image", nil)
+		return
+	}
+
+	created, err := h.images.Create(r.Context(), pi)
+	if err != nil {
+		h.deleteBlobs(r.Context(), written)
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.WriteError(w, http.StatusNotFound, "product not found", nil)
+			return
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to save image", nil)
+		return
+	}
+	h.imageURLs(&created)
+
+	response.WriteData(w, http.StatusCreated, created, nil)
+}
+
+func (h *ProductsHandler) UpdateImage(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid product id", nil)
+		return
+	}
+	imageID, err := uuid.Parse(chi.URLParam(r, "imageID"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid image id", nil)
+		return
+	}
+
+	var req model.ProductImageUpdateRequest
+	if err := response.DecodeJSON(w, r, &req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+	if req.AltText != nil {
+		v := strings.TrimSpace(*req.AltText)
+		req.AltText = &v
+	}
+	if err := h.validate.Struct(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return
+	}
+	if req.IsPrimary != nil && !*req.IsPrimary {
+		response.WriteError(w, http.StatusBadRequest, "validation error", "a product always has a primary image; mark another image as primary instead")
+		return
+	}
+
+	updated, err := h.images.Update(r.Context(), id, imageID, req.AltText, req.IsPrimary != nil)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.WriteError(w, http.StatusNotFound, "image not found", nil)
+			return
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to update image", nil)
+		return
+	}
+	h.imageURLs(&updated)
+
+	response.WriteData(w, http.StatusOK, updated, nil)
+}
+
+func (h *ProductsHandler) ReorderImages(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid product id", nil)
+		return
+	}
+
+	var req model.ProductImageOrderRequest
+	if err := response.DecodeJSON(w, r, &req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return
+	}
+
+	ids := make([]uuid.UUID, len(req.IDs))
+	for i, v := range req.IDs {
+		ids[i], _ = uuid.Parse(v)
+	}
+
+	if err := h.images.Reorder(r.Context(), id, ids); err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			response.WriteError(w, http.StatusNotFound, "product not found", nil)
+		case errors.Is(err, store.ErrImageOrder):
+			response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		default:
+			response.WriteError(w, http.StatusInternalServerError, "failed to reorder images", nil)
+		}
+		return
+	}
+
+	h.ListImages(w, r)
+}
+
+func (h *ProductsHandler) DeleteImage(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid product id", nil)
+		return
+	}
+	imageID, err := uuid.Parse(chi.URLParam(r, "imageID"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid image id", nil)
+		return
+	}
+
+	deleted, err := h.images.Delete(r.Context(), id, imageID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.WriteError(w, http.StatusNotFound, "image not found", nil)
+			return
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to delete image", nil)
+		return
+	}
+	h.deleteBlobs(r.Context(), imageKeys(deleted))
+
+	response.WriteData(w, http.StatusOK, map[string]string{"status": "deleted"}, nil)
+}
+
+// attachPrimaryImages sets PrimaryImage on each product that has one.
+func (h *ProductsHandler) attachPrimaryImages(ctx context.Context, items []model.Product) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(items))
+	for i, p := range items {
+		ids[i] = p.ID
+	}
+	primary, err := h.images.Primary(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range items {
+		if img, ok := primary[items[i].ID]; ok {
+			h.imageURLs(&img)
+			items[i].PrimaryImage = &img
+		}
+	}
+	return nil
+}
+
+// loadImages fills in the gallery and primary image of a single product.
+func (h *ProductsHandler) loadImages(ctx context.Context, p *model.Product) error {
+	images, err := h.images.List(ctx, p.ID)
+	if err != nil {
+		return err
+	}
+	for i := range images {
+		h.imageURLs(&images[i])
+		if images[i].IsPrimary {
+			img := images[i]
+			p.PrimaryImage = &img
+		}
+	}
+	p.Images = images
+	return nil
+}
+
+func (h *ProductsHandler) imageURLs(img *model.ProductImage) {
+	img.URL = h.blobs.URL(img.OriginalKey)
+	img.Thumbnails = make(map[string]string, len(img.ThumbnailKeys))
+	for name, key := range img.ThumbnailKeys {
+		img.Thumbnails[name] = h.blobs.URL(key)
+	}
+}
+
+func imageKeys(img model.ProductImage) []string {
+	keys := []string{img.OriginalKey}
+	for _, k := range img.ThumbnailKeys {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// deleteBlobs removes files whose rows are gone. Failures only leave orphaned
+// files behind, so they are logged rather than returned.
+func (h *ProductsHandler) deleteBlobs(ctx context.Context, keys []string) {
+	for _, k := range keys {
+		if err := h.blobs.Delete(ctx, k); err != nil {
+			slog.Error("failed to delete blob", "key", k, "err", err)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"mini-product-catalog/internal/model"
+	"mini-product-catalog/internal/money"
+	"mini-product-catalog/internal/response"
+	"mini-product-catalog/internal/store"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func (h *ProductsHandler) ListPrices(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid product id", nil)
+		return
+	}
+
+	items, err := h.currencies.ListProductPrices(r.Context(), id)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch product prices", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, items, map[string]any{"count": len(items)})
+}
+
+func (h *ProductsHandler) SetPrice(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid product id", nil)
+		return
+	}
+	currency, ok := parseCurrency(chi.URLParam(r, "currency"))
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "unsupported currency", nil)
+		return
+	}
+
+	var req model.ProductPriceRequest
+	if err := response.DecodeJSON(w, r, &req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return
+	}
+
+	price, err := money.Parse(req.Amount, currency)
+	if err != nil || price.Amount <= 0 {
+		response.WriteError(w, http.StatusBadRequest, "validation error", "amount must be a positive amount in "+currency)
+		return
+	}
+
+	p, err := h.products.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.WriteError(w, http.StatusNotFound, "product not found", nil)
+			return
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch product", nil)
+		return
+	}
+	if p.Price.Currency == currency {
+		response.WriteError(w, http.StatusBadRequest, "the product is already priced in "+currency+", update its price instead", nil)
+		return
+	}
+
+	saved, err := h.currencies.SetProductPrice(r.Context(), id, price)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to save product price", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, saved, nil)
+}
+
+func (h *ProductsHandler) DeletePrice(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid product id", nil)
+		return
+	}
+	currency, ok := parseCurrency(chi.URLParam(r, "currency"))
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "unsupported currency", nil)
+		return
+	}
+
+	ok, err = h.currencies.DeleteProductPrice(r.Context(), id, currency)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to delete product price", nil)
+		return
+	}
+	if !ok {
+		response.WriteError(w, http.StatusNotFound, "product price not found", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, map[string]string{"status": "deleted"}, nil)
+}
+
+// requestedCurrency reads the optional ?currency= parameter. An empty result
+// means prices stay in the currency they are stored in.
+func requestedCurrency(w http.ResponseWriter, r *http.Request) (string, bool) {
+	v := strings.TrimSpace(r.URL.Query().Get("currency"))
+	if v == "" {
+		return "", true
+	}
+	c, ok := parseCurrency(v)
+	if !ok {
+		response.WriteError(w, http.StatusBadRequest, "unsupported currency", v)
+		return "", false
+	}
+	return c, true
+}
+
+// localize rewrites prices into currency. A per-product override wins;
+// otherwise the stored price is converted at the current exchange rate and
+// rounded by the currency's rounding rule.
+func (h *ProductsHandler) localize(ctx context.Context, items []model.Product, currency string) error {
+	if currency == "" || len(items) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(items))
+	for i, p := range items {
+		ids[i] = p.ID
+	}
+	overrides, err := h.currencies.PriceOverrides(ctx, ids, currency)
+	if err != nil {
+		return err
+	}
+	rounding, err := h.currencies.Rounding(ctx, currency)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	rates := map[string]*big.Rat{}
+	convert := func(m money.Money) (money.Money, error) {
+		rate, ok := rates[m.Currency]
+		if !ok {
+			var err error
+			rate, err = h.currencies.Rate(ctx, m.Currency, currency, now)
+			if err != nil {
+				return money.Money{}, fmt.Errorf("%w from %s to %s", err, m.Currency, currency)
+			}
+			rates[m.Currency] = rate
+		}
+		return money.Convert(m, currency, rate, rounding)
+	}
+
+	for i := range items {
+		p := &items[i]
+		base := p.Price
+
+		// Overrides are per product, so variant prices and ranges are
+		// always converted.
+		if base.Currency != currency {
+			if p.PriceRange != nil {
+				if p.PriceRange.Min, err = convert(p.PriceRange.Min); err != nil {
+					return err
+				}
+				if p.PriceRange.Max, err = convert(p.PriceRange.Max); err != nil {
+					return err
+				}
+			}
+			for j := range p.Variants {
+				if p.Variants[j].Price, err = convert(p.Variants[j].Price); err != nil {
+					return err
+				}
+			}
+		}
+
+		if o, ok := overrides[p.ID]; ok {
+			p.BasePrice, p.Price, p.PriceSource = &base, o, model.PriceSourceOverride
+			continue
+		}
+		if base.Currency == currency {
+			continue
+		}
+
+		converted, err := convert(base)
+		if err != nil {
+			return err
+		}
+		p.BasePrice, p.Price, p.PriceSource = &base, converted, model.PriceSourceConverted
+	}
+
+	return nil
+}
+
+// convertPriceBuckets converts the configured facet boundaries, which are in
+// the default currency, into currency with its rounding rule.
+func (h *ProductsHandler) convertPriceBuckets(ctx context.Context, buckets []money.Money, currency string) ([]money.Money, error) {
+	if len(buckets) == 0 {
+		return nil, nil
+	}
+
+	rate, err := h.currencies.Rate(ctx, buckets[0].Currency, currency, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("%w from %s to %s", err, buckets[0].Currency, currency)
+	}
+	rounding, err := h.currencies.Rounding(ctx, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]money.Money, 0, len(buckets))
+	for _, b := range buckets {
+		m, err := money.Convert(b, currency, rate, rounding)
+		if err != nil {
+			return nil, err
+		}
+		// Rounding can merge neighbours or reach zero; neither is a boundary.
+		if m.Amount > 0 && (len(out) == 0 || out[len(out)-1].Amount < m.Amount) {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+func writeLocalizeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, store.ErrNoExchangeRate) {
+		response.WriteError(w, http.StatusUnprocessableEntity, "price not available in the requested currency", err.Error())
+		return
+	}
+	response.WriteError(w, http.StatusInternalServerError, "failed to convert prices", nil)
+}
@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"errors"
+	"mini-product-catalog/internal/model"
+	"mini-product-catalog/internal/response"
+	"mini-product-catalog/internal/store"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func (h *ProductsHandler) ListVariants(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid product id", nil)
+		return
+	}
+
+	p, err := h.products.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.WriteError(w, http.StatusNotFound, "product not found", nil)
+			return
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch product", nil)
+		return
+	}
+	if err := h.loadVariants(r, &p); err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch variants", nil)
+		return
+	}
+
+	currency, ok := requestedCurrency(w, r)
+	if !ok {
+		return
+	}
+	items := []model.Product{p}
+	if err := h.localize(r.Context(), items, currency); err != nil {
+		writeLocalizeError(w, err)
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, items[0].Variants, map[string]any{
+		"options": items[0].Options,
+		"count":   len(items[0].Variants),
+	})
+}
+
+// SetOptions replaces the option axes of a product. Option names and values
+// keep the order given, which is the order clients should display them in.
+func (h *ProductsHandler) SetOptions(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid product id", nil)
+		return
+	}
+
+	var req model.ProductOptionsRequest
+	if err := response.DecodeJSON(w, r, &req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+
+	for i := range req.Options {
+		o := &req.Options[i]
+		o.Name = strings.ToLower(strings.TrimSpace(o.Name))
+		for j := range o.Values {
+			o.Values[j] = strings.TrimSpace(o.Values[j])
+		}
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return
+	}
+	names := map[string]bool{}
+	for _, o := range req.Options {
+		if names[o.Name] {
+			response.WriteError(w, http.StatusBadRequest, "validation error", "duplicate option "+o.Name)
+			return
+		}
+		names[o.Name] = true
+
+		values := slices.Clone(o.Values)
+		slices.Sort(values)
+		if len(slices.Compact(values)) != len(o.Values) {
+			response.WriteError(w, http.StatusBadRequest, "validation error", "duplicate value in option "+o.Name)
+			return
+		}
+	}
+
+	if req.Options == nil {
+		req.Options = []model.ProductOption{}
+	}
+
+	if err := h.variants.SetOptions(r.Context(), id, req.Options); err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			response.WriteError(w, http.StatusNotFound, "product not found", nil)
+		case errors.Is(err, store.ErrOptionsInUse):
+			response.WriteError(w, http.StatusConflict, "existing variants use options or values that would be removed", nil)
+		default:
+			response.WriteError(w, http.StatusInternalServerError, "failed to save options", nil)
+		}
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, req.Options, nil)
+}
+
+func (h *ProductsHandler) CreateVariant(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid product id", nil)
+		return
+	}
+
+	req, ok := h.decodeVariant(w, r)
+	if !ok {
+		return
+	}
+
+	created, err := h.variants.Create(r.Context(), id, req.SKU, req.Options, req.Price)
+	if err != nil {
+		writeVariantError(w, err, "failed to create variant")
+		return
+	}
+
+	response.WriteData(w, http.StatusCreated, created, nil)
+}
+
+func (h *ProductsHandler) UpdateVariant(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid product id", nil)
+		return
+	}
+	variantID, err := uuid.Parse(chi.URLParam(r, "variantID"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid variant id", nil)
+		return
+	}
+
+	req, ok := h.decodeVariant(w, r)
+	if !ok {
+		return
+	}
+
+	updated, err := h.variants.Update(r.Context(), id, variantID, req.SKU, req.Options, req.Price)
+	if err != nil {
+		writeVariantError(w, err, "failed to update variant")
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, updated, nil)
+}
+
+func (h *ProductsHandler) DeleteVariant(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid product id", nil)
+		return
+	}
+	variantID, err := uuid.Parse(chi.URLParam(r, "variantID"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid variant id", nil)
+		return
+	}
+
+	ok, err := h.variants.Delete(r.Context(), id, variantID)
+	if err != nil {
+		if errors.Is(err, store.ErrStockReserved) {
+			response.WriteError(w, http.StatusConflict, "stock is reserved for this variant; release it before deleting", nil)
+			return
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to delete variant", nil)
+		return
+	}
+	if !ok {
+		response.WriteError(w, http.StatusNotFound, "variant not found", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, map[string]string{"status": "deleted"}, nil)
+}
+
+func (h *ProductsHandler) decodeVariant(w http.ResponseWriter, r *http.Request) (model.VariantRequest, bool) {
+	var req model.VariantRequest
+	if err := response.DecodeJSON(w, r, &req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return req, false
+	}
+
+	req.SKU = strings.ToUpper(strings.TrimSpace(req.SKU))
+	options := make(map[string]string, len(req.Options))
+	for k, v := range req.Options {
+		options[strings.ToLower(strings.TrimSpace(k))] = strings.TrimSpace(v)
+	}
+	req.Options = options
+
+	if err := h.validate.Struct(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return req, false
+	}
+	if !skuRe.MatchString(req.SKU) {
+		response.WriteError(w, http.StatusBadRequest, "validation error", "sku may only contain letters, digits, '.', '_' and '-'")
+		return req, false
+	}
+
+	return req, true
+}
+
+func writeVariantError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		response.WriteError(w, http.StatusNotFound, "product or variant not found", nil)
+	case errors.Is(err, store.ErrSKUTaken):
+		response.WriteError(w, http.StatusConflict, "sku already exists", nil)
+	case store.IsUniqueViolation(err):
+		// The SKU is claimed up front, so this is the (product_id, options) key.
+		response.WriteError(w, http.StatusConflict, "a variant with these options already exists", nil)
+	case errors.Is(err, store.ErrInvalidVariantOptions):
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+	case errors.Is(err, store.ErrVariantCurrency):
+		response.WriteError(w, http.StatusBadRequest, "validation error", "variant prices must be in the product's currency")
+	default:
+		response.WriteError(w, http.StatusInternalServerError, msg, nil)
+	}
+}
+
+// loadVariants fills in the options and variants of a single product.
+func (h *ProductsHandler) loadVariants(r *http.Request, p *model.Product) error {
+	var err error
+	if p.Options, err = h.variants.ListOptions(r.Context(), p.ID); err != nil {
+		return err
+	}
+	p.Variants, err = h.variants.List(r.Context(), p.ID)
+	return err
+}
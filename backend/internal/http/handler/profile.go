@@ -0,0 +1,225 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"mini-product-catalog/internal/middleware"
+	"mini-product-catalog/internal/model"
+	"mini-product-catalog/internal/response"
+	"mini-product-catalog/internal/store"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func (h *AuthHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	cur, ok := middleware.CurrentUserFromContext(r.Context())
+	if !ok {
+		response.WriteError(w, http.StatusUnauthorized, "unauthorized", nil)
+		return
+	}
+
+	var req model.ProfileUpdateRequest
+	if err := response.DecodeJSON(w, r, &req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		req.Name = &name
+	}
+	if req.Email != nil {
+		email := strings.TrimSpace(strings.ToLower(*req.Email))
+		req.Email = &email
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return
+	}
+
+	u, ok := h.loadSelf(w, r, cur.ID)
+	if !ok {
+		return
+	}
+
+	emailChanged := req.Email != nil && *req.Email != u.Email
+	if emailChanged {
+		if req.CurrentPassword == "" {
+			response.WriteError(w, http.StatusBadRequest, "validation error", "current_password is required to change email")
+			return
+		}
+		if !h.confirmPassword(w, r, u, req.CurrentPassword) {
+			return
+		}
+	} else {
+		req.Email = nil
+	}
+
+	updated, err := h.users.UpdateProfile(r.Context(), u.ID, req.Name, req.Email)
+	if err != nil {
+		if store.IsUniqueViolation(err) {
+			response.WriteError(w, http.StatusConflict, "email already registered", nil)
+			return
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to update profile", nil)
+		return
+	}
+
+	if !emailChanged {
+		response.WriteData(w, http.StatusOK, updated, nil)
+		return
+	}
+
+	if err := h.sendVerification(r.Context(), updated); err != nil {
+		slog.Error("failed to send verification email", "user_id", updated.ID, "err", err)
+	}
+
+	// A new email bumps the token version so no access token still claims it
+	// is verified. Replace the caller's session with one that matches.
+	if familyID, err := uuid.Parse(cur.SessionID); err == nil {
+		if err := h.refreshTokens.RevokeFamily(r.Context(), familyID); err != nil {
+			response.WriteError(w, http.StatusInternalServerError, "failed to revoke session", nil)
+			return
+		}
+	}
+	session, err := h.startSession(r.Context(), updated, cur.MFA)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to generate token", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, updated, map[string]any{"session": session})
+}
+
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	cur, ok := middleware.CurrentUserFromContext(r.Context())
+	if !ok {
+		response.WriteError(w, http.StatusUnauthorized, "unauthorized", nil)
+		return
+	}
+
+	var req model.ChangePasswordRequest
+	if err := response.DecodeJSON(w, r, &req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return
+	}
+
+	u, ok := h.loadSelf(w, r, cur.ID)
+	if !ok {
+		return
+	}
+	if !h.confirmPassword(w, r, u, req.CurrentPassword) {
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to hash password", nil)
+		return
+	}
+
+	if err := h.users.UpdatePassword(r.Context(), u.ID, string(hash)); err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to change password", nil)
+		return
+	}
+	if err := h.refreshTokens.RevokeAllForUser(r.Context(), u.ID); err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to revoke sessions", nil)
+		return
+	}
+
+	// Changing the password bumps the token version, which signs out every
+	// session including this one, so hand the caller a fresh session.
+	u, ok = h.loadSelf(w, r, u.ID)
+	if !ok {
+		return
+	}
+	resp, err := h.startSession(r.Context(), u, cur.MFA)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to generate token", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, resp, nil)
+}
+
+func (h *AuthHandler) DeleteMe(w http.ResponseWriter, r *http.Request) {
+	cur, ok := middleware.CurrentUserFromContext(r.Context())
+	if !ok {
+		response.WriteError(w, http.StatusUnauthorized, "unauthorized", nil)
+		return
+	}
+
+	var req model.DeleteAccountRequest
+	if err := response.DecodeJSON(w, r, &req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return
+	}
+
+	u, ok := h.loadSelf(w, r, cur.ID)
+	if !ok {
+		return
+	}
+	if !h.confirmPassword(w, r, u, req.Password) {
+		return
+	}
+
+	last, err := h.users.LastWithPermission(r.Context(), u.ID, model.PermissionRolesManage)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to close account", nil)
+		return
+	}
+	if last {
+		response.WriteError(w, http.StatusConflict, "the last account that can manage roles cannot be closed", nil)
+		return
+	}
+
+	if _, err := h.users.Delete(r.Context(), u.ID); err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to close account", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, map[string]string{"status": "account closed"}, nil)
+}
+
+func (h *AuthHandler) loadSelf(w http.ResponseWriter, r *http.Request, id uuid.UUID) (model.User, bool) {
+	u, err := h.users.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.WriteError(w, http.StatusUnauthorized, "user not found", nil)
+			return model.User{}, false
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch profile", nil)
+		return model.User{}, false
+	}
+	return u, true
+}
+
+// confirmPassword re-checks the user's password for sensitive changes. Wrong
+// guesses count towards the same lockout as failed logins.
+func (h *AuthHandler) confirmPassword(w http.ResponseWriter, r *http.Request, u model.User, password string) bool {
+	accountKey := "email:" + u.Email
+	if h.checkLocked(w, r, accountKey) {
+		return false
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) != nil {
+		h.recordFailure(r.Context(), accountKey, h.cfg.AccountLockout)
+		response.WriteError(w, http.StatusForbidden, "current password is incorrect", nil)
+		return false
+	}
+
+	return true
+}
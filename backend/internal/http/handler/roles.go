@@ -0,0 +1,225 @@
+package handler
+
+import (
+	"errors"
+	"mini-product-catalog/internal/middleware"
+	"mini-product-catalog/internal/model"
+	"mini-product-catalog/internal/response"
+	"mini-product-catalog/internal/store"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var roleNameRe = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// builtinRoles are referenced by code (registration, MFA policy) and cannot
+// be deleted. The admin role's permissions are also fixed.
+var builtinRoles = map[string]bool{"admin": true, "user": true}
+
+type RolesHandler struct {
+	roles    *store.RoleStore
+	users    *store.UserStore
+	validate *validator.Validate
+}
+
+func NewRolesHandler(roles *store.RoleStore, users *store.UserStore, validate *validator.Validate) *RolesHandler {
+	return &RolesHandler{roles: roles, users: users, validate: validate}
+}
+
+func (h *RolesHandler) List(w http.ResponseWriter, r *http.Request) {
+	items, err := h.roles.List(r.Context())
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch roles", nil)
+		return
+	}
+
+	meta := map[string]any{
+		"count": len(items),
+	}
+	response.WriteData(w, http.StatusOK, items, meta)
+}
+
+func (h *RolesHandler) ListPermissions(w http.ResponseWriter, r *http.Request) {
+	items, err := h.roles.ListPermissions(r.Context())
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch permissions", nil)
+		return
+	}
+
+	meta := map[string]any{
+		"count": len(items),
+	}
+	response.WriteData(w, http.StatusOK, items, meta)
+}
+
+func (h *RolesHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req model.RoleCreateRequest
+	if err := response.DecodeJSON(w, r, &req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+
+	req.Name = strings.TrimSpace(strings.ToLower(req.Name))
+	req.Description = strings.TrimSpace(req.Description)
+
+	if err := h.validate.Struct(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return
+	}
+	if !roleNameRe.MatchString(req.Name) {
+		response.WriteError(w, http.StatusBadRequest, "validation error", "name must be lowercase letters, digits and underscores")
+		return
+	}
+
+	created, err := h.roles.Create(r.Context(), req.Name, req.Description, req.Permissions)
+	if err != nil {
+		if store.IsUniqueViolation(err) {
+			response.WriteError(w, http.StatusConflict, "role already exists", nil)
+			return
+		}
+		if store.IsForeignKeyViolation(err) {
+			response.WriteError(w, http.StatusBadRequest, "unknown permission", nil)
+			return
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to create role", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusCreated, created, nil)
+}
+
+func (h *RolesHandler) Update(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if name == "admin" {
+		response.WriteError(w, http.StatusForbidden, "the admin role cannot be modified", nil)
+		return
+	}
+
+	var req model.RoleUpdateRequest
+	if err := response.DecodeJSON(w, r, &req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+
+	req.Description = strings.TrimSpace(req.Description)
+
+	if err := h.validate.Struct(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return
+	}
+
+	updated, err := h.roles.Update(r.Context(), name, req.Description, req.Permissions)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.WriteError(w, http.StatusNotFound, "role not found", nil)
+			return
+		}
+		if store.IsForeignKeyViolation(err) {
+			response.WriteError(w, http.StatusBadRequest, "unknown permission", nil)
+			return
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to update role", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, updated, nil)
+}
+
+func (h *RolesHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if builtinRoles[name] {
+		response.WriteError(w, http.StatusForbidden, "built-in roles cannot be deleted", nil)
+		return
+	}
+
+	ok, err := h.roles.Delete(r.Context(), name)
+	if err != nil {
+		if store.IsForeignKeyViolation(err) {
+			response.WriteError(w, http.StatusConflict, "role is assigned to users", nil)
+			return
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to delete role", nil)
+		return
+	}
+	if !ok {
+		response.WriteError(w, http.StatusNotFound, "role not found", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, map[string]string{"status": "deleted"}, nil)
+}
+
+// AssignUserRole refuses to change the caller's own role, like
+// AdminUsersHandler.Update, and to take roles:manage from the last enabled
+// user who has it.
+func (h *RolesHandler) AssignUserRole(w http.ResponseWriter, r *http.Request) {
+	cur, ok := middleware.CurrentUserFromContext(r.Context())
+	if !ok {
+		response.WriteError(w, http.StatusUnauthorized, "unauthorized", nil)
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid user id", nil)
+		return
+	}
+
+	var req model.UserRoleRequest
+	if err := response.DecodeJSON(w, r, &req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+
+	req.Role = strings.TrimSpace(strings.ToLower(req.Role))
+
+	if err := h.validate.Struct(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return
+	}
+
+	if id == cur.ID {
+		response.WriteError(w, http.StatusForbidden, "you cannot change your own role", nil)
+		return
+	}
+
+	perms, err := h.roles.Permissions(r.Context(), req.Role)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to resolve permissions", nil)
+		return
+	}
+	if !slices.Contains(perms, model.PermissionRolesManage) {
+		last, err := h.users.LastWithPermission(r.Context(), id, model.PermissionRolesManage)
+		if err != nil {
+			response.WriteError(w, http.StatusInternalServerError, "failed to assign role", nil)
+			return
+		}
+		if last {
+			response.WriteError(w, http.StatusConflict, "the last user who can manage roles must keep that permission", nil)
+			return
+		}
+	}
+
+	u, err := h.users.Update(r.Context(), id, store.UserUpdate{Role: &req.Role})
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			response.WriteError(w, http.StatusNotFound, "user not found", nil)
+		case errors.Is(err, store.ErrRoleNotFound):
+			response.WriteError(w, http.StatusBadRequest, "unknown role", nil)
+		default:
+			response.WriteError(w, http.StatusInternalServerError, "failed to assign role", nil)
+		}
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, u, nil)
+}
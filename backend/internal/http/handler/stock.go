@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"errors"
+	"mini-product-catalog/internal/middleware"
+	"mini-product-catalog/internal/model"
+	"mini-product-catalog/internal/response"
+	"mini-product-catalog/internal/store"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type StockHandler struct {
+	stock    *store.StockStore
+	validate *validator.Validate
+}
+
+func NewStockHandler(stock *store.StockStore, validate *validator.Validate) *StockHandler {
+	return &StockHandler{stock: stock, validate: validate}
+}
+
+func (h *StockHandler) Receive(w http.ResponseWriter, r *http.Request) {
+	h.applyQuantity(w, r, model.StockReceive, 1, 0)
+}
+
+func (h *StockHandler) Reserve(w http.ResponseWriter, r *http.Request) {
+	h.applyQuantity(w, r, model.StockReserve, 0, 1)
+}
+
+func (h *StockHandler) Release(w http.ResponseWriter, r *http.Request) {
+	h.applyQuantity(w, r, model.StockRelease, 0, -1)
+}
+
+// Ship takes reserved units out of stock.
+func (h *StockHandler) Ship(w http.ResponseWriter, r *http.Request) {
+	h.applyQuantity(w, r, model.StockShip, -1, -1)
+}
+
+func (h *StockHandler) Adjust(w http.ResponseWriter, r *http.Request) {
+	var req model.StockAdjustRequest
+	if err := response.DecodeJSON(w, r, &req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	req.Reference = strings.TrimSpace(req.Reference)
+
+	if err := h.validate.Struct(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return
+	}
+
+	h.apply(w, r, store.StockChange{
+		Kind:        model.StockAdjust,
+		OnHandDelta: req.Delta,
+		Reason:      req.Reason,
+		Reference:   req.Reference,
+	})
+}
+
+func (h *StockHandler) Movements(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid product id", nil)
+		return
+	}
+
+	variantID, ok := stockVariantID(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	page := parseInt(q.Get("page"), 1)
+	limit := parseInt(q.Get("limit"), 20)
+
+	items, total, err := h.stock.ListMovements(r.Context(), id, variantID, page, limit)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to fetch stock movements", nil)
+		return
+	}
+
+	meta := map[string]any{
+		"page":  page,
+		"limit": limit,
+		"total": total,
+	}
+
+	response.WriteData(w, http.StatusOK, items, meta)
+}
+
+// applyQuantity handles the endpoints that move a positive quantity; the
+// signs say which way each counter goes.
+func (h *StockHandler) applyQuantity(w http.ResponseWriter, r *http.Request, kind string, onHandSign, reservedSign int) {
+	var req model.StockQuantityRequest
+	if err := response.DecodeJSON(w, r, &req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	req.Reference = strings.TrimSpace(req.Reference)
+
+	if err := h.validate.Struct(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return
+	}
+	if req.Reason == "" {
+		req.Reason = kind
+	}
+
+	h.apply(w, r, store.StockChange{
+		Kind:          kind,
+		OnHandDelta:   onHandSign * req.Quantity,
+		ReservedDelta: reservedSign * req.Quantity,
+		Reason:        req.Reason,
+		Reference:     req.Reference,
+	})
+}
+
+func (h *StockHandler) apply(w http.ResponseWriter, r *http.Request, c store.StockChange) {
+	cur, ok := middleware.CurrentUserFromContext(r.Context())
+	if !ok {
+		response.WriteError(w, http.StatusUnauthorized, "unauthorized", nil)
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid product id", nil)
+		return
+	}
+
+	variantID, ok := stockVariantID(w, r)
+	if !ok {
+		return
+	}
+
+	c.VariantID = variantID
+	c.ActorKind = cur.Kind
+	c.ActorID = cur.ID
+
+	m, err := h.stock.Apply(r.Context(), id, c)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows) && variantID != nil:
+			response.WriteError(w, http.StatusNotFound, "variant not found", nil)
+		case errors.Is(err, pgx.ErrNoRows):
+			response.WriteError(w, http.StatusNotFound, "product not found", nil)
+		case errors.Is(err, store.ErrInsufficientStock):
+			response.WriteError(w, http.StatusConflict, "insufficient stock", nil)
+		default:
+			response.WriteError(w, http.StatusInternalServerError, "failed to update stock", nil)
+		}
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, m, nil)
+}
+
+// stockVariantID reads the variant from routes mounted under
+// /variants/{variantID}; nil means the product's own stock.
+func stockVariantID(w http.ResponseWriter, r *http.Request) (*uuid.UUID, bool) {
+	v := chi.URLParam(r, "variantID")
+	if v == "" {
+		return nil, true
+	}
+	id, err := uuid.Parse(v)
+	if err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid variant id", nil)
+		return nil, false
+	}
+	return &id, true
+}
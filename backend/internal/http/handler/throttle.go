@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"mini-product-catalog/internal/auth"
+	"mini-product-catalog/internal/response"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// dummyPasswordHash is compared against when the account does not exist so
+// that unknown emails take as long to reject as wrong passwords.
+const dummyPasswordHash = "$2a$10$2zG52EfiycptLsaWctrdjO8WwH/ah0GWlGmGU9jhZ.EVLWzpr7sKK"
+
+// clientIP returns the address set by chi's RealIP middleware, without port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func setRetryAfter(w http.ResponseWriter, until time.Time) {
+	secs := int(math.Ceil(time.Until(until).Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+}
+
+// checkLocked writes a 429 response and returns true if any of keys is
+// currently locked out.
+func (h *AuthHandler) checkLocked(w http.ResponseWriter, r *http.Request, keys ...string) bool {
+	until, err := h.throttles.LockedUntil(r.Context(), keys...)
+	if err != nil {
+		response.WriteError(w, http.StatusInternalServerError, "failed to login", nil)
+		return true
+	}
+	if until.IsZero() {
+		return false
+	}
+
+	setRetryAfter(w, until)
+	response.WriteError(w, http.StatusTooManyRequests, "too many failed attempts, try again later", map[string]any{
+		"retry_at": until,
+	})
+	return true
+}
+
+func (h *AuthHandler) recordFailure(ctx context.Context, key string, policy auth.LockoutPolicy) {
+	failures, err := h.throttles.RecordFailure(ctx, key, time.Now().Add(-policy.Window))
+	if err != nil {
+		slog.Error("failed to record login failure", "key", key, "err", err)
+		return
+	}
+
+	if d := policy.LockFor(failures); d > 0 {
+		if err := h.throttles.Lock(ctx, key, time.Now().Add(d)); err != nil {
+			slog.Error("failed to lock login", "key", key, "err", err)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"mini-product-catalog/internal/auth"
+	"mini-product-catalog/internal/mail"
+	"mini-product-catalog/internal/model"
+	"mini-product-catalog/internal/response"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimSpace(r.URL.Query().Get("token"))
+	if token == "" {
+		response.WriteError(w, http.StatusBadRequest, "token is required", nil)
+		return
+	}
+
+	t, err := h.userTokens.Consume(r.Context(), model.TokenPurposeEmailVerification, auth.HashToken(token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.WriteError(w, http.StatusBadRequest, "invalid or expired verification token", nil)
+			return
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to verify email", nil)
+		return
+	}
+
+	u, err := h.users.MarkEmailVerified(r.Context(), t.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.WriteError(w, http.StatusBadRequest, "invalid or expired verification token", nil)
+			return
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to verify email", nil)
+		return
+	}
+
+	response.WriteData(w, http.StatusOK, u, nil)
+}
+
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var req model.ResendVerificationRequest
+	if err := response.DecodeJSON(w, r, &req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+
+	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
+
+	if err := h.validate.Struct(req); err != nil {
+		response.WriteError(w, http.StatusBadRequest, "validation error", err.Error())
+		return
+	}
+
+	// Unknown, verified and recently mailed accounts all get the same answer,
+	// sent before any lookup as in ForgotPassword, so the endpoint doesn't
+	// reveal which emails are registered.
+	response.WriteData(w, http.StatusAccepted, map[string]string{"status": "if the account exists and is unverified, a verification link has been sent"}, nil)
+
+	afterResponse(r, "resend verification email", func(ctx context.Context) error {
+		u, err := h.users.GetByEmail(ctx, req.Email)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if u.EmailVerifiedAt != nil {
+			return nil
+		}
+
+		last, err := h.userTokens.LastIssuedAt(ctx, u.ID, model.TokenPurposeEmailVerification)
+		if err != nil {
+			return err
+		}
+		if last != nil && time.Now().Before(last.Add(h.cfg.VerificationResendCooldown)) {
+			return nil
+		}
+
+		return h.sendVerification(ctx, u)
+	})
+}
+
+func (h *AuthHandler) sendVerification(ctx context.Context, u model.User) error {
+	token, err := auth.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	if _, err := h.userTokens.Create(ctx, u.ID, model.TokenPurposeEmailVerification, auth.HashToken(token), time.Now().Add(h.cfg.EmailVerificationTTL)); err != nil {
+		return err
+	}
+
+	link := strings.TrimRight(h.cfg.AppBaseURL, "/") + "/verify-email?token=" + url.QueryEscape(token)
+	return h.mailer.Send(ctx, mail.EmailVerificationMessage(u.Email, u.Name, link, h.cfg.EmailVerificationTTL))
+}
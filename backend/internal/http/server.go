@@ -2,11 +2,19 @@ package http
 
 import (
 	"log/slog"
+	"mini-product-catalog/internal/auth"
+	"mini-product-catalog/internal/blob"
 	"mini-product-catalog/internal/config"
 	"mini-product-catalog/internal/http/handler"
+	"mini-product-catalog/internal/mail"
 	"mini-product-catalog/internal/middleware"
+	"mini-product-catalog/internal/model"
+	"mini-product-catalog/internal/money"
+	"mini-product-catalog/internal/oidc"
 	"mini-product-catalog/internal/store"
 	nethttp "net/http"
+	"reflect"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
@@ -15,7 +23,7 @@ import (
 	chimw "github.com/go-chi/chi/v5/middleware"
 )
 
-func NewServer(cfg config.Config, logger *slog.Logger, db *pgxpool.Pool) nethttp.Handler {
+func NewServer(cfg config.Config, logger *slog.Logger, db *pgxpool.Pool, keys *auth.KeySet) nethttp.Handler {
 	r := chi.NewRouter()
 
 	r.Use(chimw.RequestID)
@@ -27,52 +35,279 @@ func NewServer(cfg config.Config, logger *slog.Logger, db *pgxpool.Pool) nethttp
 	r.Use(middleware.RequestLogger(logger))
 
 	validate := validator.New()
+	// Validate money by its amount in minor units, so tags like gt=0 work.
+	validate.RegisterCustomTypeFunc(func(v reflect.Value) any {
+		return v.Interface().(money.Money).Amount
+	}, money.Money{})
 
 	userStore := store.NewUserStore(db)
+	refreshTokenStore := store.NewRefreshTokenStore(db)
+	userTokenStore := store.NewUserTokenStore(db)
+	mfaStore := store.NewMFAStore(db)
+	loginThrottleStore := store.NewLoginThrottleStore(db)
+	apiKeyStore := store.NewAPIKeyStore(db)
+	roleStore := store.NewRoleStore(db)
+	oidcStore := store.NewOIDCStore(db)
 	categoryStore := store.NewCategoryStore(db)
 	productStore := store.NewProductStore(db)
+	variantStore := store.NewVariantStore(db)
+	imageStore := store.NewImageStore(db)
+	currencyStore := store.NewCurrencyStore(db)
+	stockStore := store.NewStockStore(db)
+
+	blobs := blob.NewLocalStore(cfg.BlobDir, cfg.MediaBaseURL)
 
 	healthHandler := handler.NewHealthHandler()
+	jwksHandler := handler.NewJWKSHandler(keys)
 	categoriesHandler := handler.NewCategoriesHandler(categoryStore, validate)
-	productsHandler := handler.NewProductsHandler(productStore, categoryStore, validate)
-	authHandler := handler.NewAuthHandler(userStore, validate, cfg.JWTSecret)
+	currenciesHandler := handler.NewCurrenciesHandler(currencyStore, validate)
+	stockHandler := handler.NewStockHandler(stockStore, validate)
+	productsHandler := handler.NewProductsHandler(productStore, variantStore, imageStore, categoryStore, currencyStore, blobs, validate, handler.ProductsConfig{
+		PriceFacetBuckets: cfg.PriceFacetBuckets,
+		MaxImageBytes:     int64(cfg.MaxImageBytes),
+	})
+	authHandler := handler.NewAuthHandler(userStore, refreshTokenStore, userTokenStore, mfaStore, loginThrottleStore, roleStore, oidcStore, newMailer(cfg), validate, handler.AuthConfig{
+		Keys:             keys,
+		AccessTokenTTL:   cfg.AccessTokenTTL,
+		RefreshTokenTTL:  cfg.RefreshTokenTTL,
+		PasswordResetTTL: cfg.PasswordResetTTL,
+		AppBaseURL:       cfg.AppBaseURL,
+
+		EmailVerification:          cfg.EmailVerification,
+		EmailVerificationTTL:       cfg.EmailVerificationTTL,
+		VerificationResendCooldown: cfg.VerificationResendCooldown,
+
+		MFAIssuer:           cfg.MFAIssuer,
+		MFAChallengeTTL:     cfg.MFAChallengeTTL,
+		MFARequiredForStaff: cfg.MFARequiredForAdmins,
+
+		AccountLockout: auth.LockoutPolicy{
+			MaxAttempts: cfg.LoginMaxAttempts,
+			Window:      cfg.LoginFailureWindow,
+			BaseDelay:   cfg.LoginLockoutBase,
+			MaxDelay:    cfg.LoginLockoutMax,
+		},
+		IPLockout: auth.LockoutPolicy{
+			MaxAttempts: cfg.LoginIPMaxAttempts,
+			Window:      cfg.LoginFailureWindow,
+			BaseDelay:   cfg.LoginLockoutBase,
+			MaxDelay:    cfg.LoginLockoutMax,
+		},
+
+		OIDCProviders: newOIDCProviders(cfg),
+	})
+	adminUsersHandler := handler.NewAdminUsersHandler(userStore, refreshTokenStore, loginThrottleStore, roleStore, validate)
+	apiKeysHandler := handler.NewAPIKeysHandler(apiKeyStore, validate)
+	rolesHandler := handler.NewRolesHandler(roleStore, userStore, validate)
+
+	authenticate := middleware.AuthMiddleware(keys, apiKeyStore, userStore, refreshTokenStore)
+
+	requirePermission := func(perm string) func(nethttp.Handler) nethttp.Handler {
+		return middleware.RequirePermission(roleStore, perm)
+	}
+
+	staff := []func(nethttp.Handler) nethttp.Handler{authenticate}
+	if cfg.EmailVerification == "routes" {
+		staff = append(staff, middleware.RequireVerifiedEmail())
+	}
+	if cfg.MFARequiredForAdmins {
+		staff = append(staff, middleware.RequireMFA(roleStore))
+	}
+	staffOnly := func(r chi.Router) {
+		r.Use(staff...)
+	}
+
+	// Public catalog reads stay anonymous, except that asking for hidden
+	// categories, or products in them, takes staff access with perm.
+	includeHidden := func(perm string) func(nethttp.Handler) nethttp.Handler {
+		return func(next nethttp.Handler) nethttp.Handler {
+			gated := chi.Chain(append(staff, requirePermission(perm))...).Handler(next)
+			return nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+				if r.URL.Query().Get("include_hidden") == "true" {
+					gated.ServeHTTP(w, r)
+					return
+				}
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
 
 	r.Get("/health", healthHandler.Health)
+	r.Get("/.well-known/jwks.json", jwksHandler.JWKS)
+	r.Mount("/media", nethttp.StripPrefix("/media", blobs.Handler()))
 
 	r.Route("/auth", func(r chi.Router) {
 		r.Post("/register", authHandler.Register)
 		r.Post("/login", authHandler.Login)
+		r.Post("/refresh", authHandler.Refresh)
+		r.Post("/logout", authHandler.Logout)
+		r.Post("/password/forgot", authHandler.ForgotPassword)
+		r.Post("/password/reset", authHandler.ResetPassword)
+		r.Get("/verify", authHandler.VerifyEmail)
+		r.Post("/verify/resend", authHandler.ResendVerification)
+		r.Post("/mfa/verify", authHandler.VerifyMFA)
+
+		r.Get("/oidc/providers", authHandler.ListOIDCProviders)
+		r.Get("/oidc/{provider}/start", authHandler.StartOIDC)
+		r.Get("/oidc/{provider}/callback", authHandler.OIDCCallback)
+		r.Post("/oidc/exchange", authHandler.ExchangeOIDC)
 	})
 
 	r.Group(func(r chi.Router) {
-		r.Use(middleware.AuthMiddleware(cfg.JWTSecret))
+		r.Use(authenticate)
+		r.Use(middleware.RequireUser())
 		r.Get("/me", authHandler.Me)
+		r.Patch("/me", authHandler.UpdateMe)
+		r.Delete("/me", authHandler.DeleteMe)
+		r.Post("/me/password", authHandler.ChangePassword)
+		r.Post("/me/mfa/setup", authHandler.SetupMFA)
+		r.Post("/me/mfa/confirm", authHandler.ConfirmMFA)
+		r.Delete("/me/mfa", authHandler.DisableMFA)
+	})
+
+	r.Route("/admin", func(r chi.Router) {
+		staffOnly(r)
+		r.Group(func(r chi.Router) {
+			r.Use(requirePermission(model.PermissionUsersRead))
+			r.Get("/users", adminUsersHandler.List)
+			r.Get("/users/{id}", adminUsersHandler.Get)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(requirePermission(model.PermissionUsersWrite))
+			r.Patch("/users/{id}", adminUsersHandler.Update)
+			r.Delete("/users/{id}", adminUsersHandler.Delete)
+			r.Post("/users/{id}/unlock", adminUsersHandler.Unlock)
+		})
+		r.With(requirePermission(model.PermissionRolesManage)).Put("/users/{id}/role", rolesHandler.AssignUserRole)
+
+		r.Group(func(r chi.Router) {
+			r.Use(requirePermission(model.PermissionRolesManage))
+			r.Get("/roles", rolesHandler.List)
+			r.Post("/roles", rolesHandler.Create)
+			r.Put("/roles/{name}", rolesHandler.Update)
+			r.Delete("/roles/{name}", rolesHandler.Delete)
+			r.Get("/permissions", rolesHandler.ListPermissions)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(requirePermission(model.PermissionAPIKeysManage))
+			r.Get("/api-keys", apiKeysHandler.List)
+			r.Post("/api-keys", apiKeysHandler.Create)
+			r.Delete("/api-keys/{id}", apiKeysHandler.Revoke)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(requirePermission(model.PermissionCurrenciesManage))
+			r.Get("/exchange-rates", currenciesHandler.ListRates)
+			r.Post("/exchange-rates", currenciesHandler.CreateRate)
+			r.Delete("/exchange-rates/{id}", currenciesHandler.DeleteRate)
+			r.Get("/rounding-rules", currenciesHandler.ListRoundingRules)
+			r.Put("/rounding-rules/{currency}", currenciesHandler.SetRoundingRule)
+			r.Delete("/rounding-rules/{currency}", currenciesHandler.DeleteRoundingRule)
+		})
 	})
 
 	r.Route("/categories", func(r chi.Router) {
-		r.Get("/", categoriesHandler.List)
+		r.With(includeHidden(model.ScopeCategoriesRead)).Get("/", categoriesHandler.List)
+		r.With(includeHidden(model.ScopeCategoriesRead)).Get("/tree", categoriesHandler.Tree)
+		r.With(includeHidden(model.ScopeCategoriesRead)).Get("/by-slug/{slug}", categoriesHandler.GetBySlug)
+		r.Get("/{id}/attributes", categoriesHandler.ListAttributes)
 
 		r.Group(func(r chi.Router) {
-			r.Use(middleware.AuthMiddleware(cfg.JWTSecret))
-			r.Use(middleware.RequireRole("admin"))
+			staffOnly(r)
+			r.Use(requirePermission(model.ScopeCategoriesWrite))
 			r.Post("/", categoriesHandler.Create)
+			r.Put("/order", categoriesHandler.Order)
 			r.Put("/{id}", categoriesHandler.Update)
 			r.Delete("/{id}", categoriesHandler.Delete)
+			r.Put("/{id}/attributes/{key}", categoriesHandler.SetAttribute)
+			r.Delete("/{id}/attributes/{key}", categoriesHandler.DeleteAttribute)
 		})
 	})
 
 	r.Route("/products", func(r chi.Router) {
-		r.Get("/", productsHandler.List)
-		r.Get("/{id}", productsHandler.Get)
+		r.With(includeHidden(model.ScopeProductsRead)).Get("/", productsHandler.List)
+		r.With(includeHidden(model.ScopeProductsRead)).Get("/{id}", productsHandler.Get)
+		r.With(includeHidden(model.ScopeProductsRead)).Get("/by-slug/{slug}", productsHandler.GetBySlug)
+		r.With(includeHidden(model.ScopeProductsRead)).Get("/by-sku/{sku}", productsHandler.GetBySKU)
+		r.Get("/{id}/variants", productsHandler.ListVariants)
+		r.Get("/{id}/images", productsHandler.ListImages)
 
 		r.Group(func(r chi.Router) {
-			r.Use(middleware.AuthMiddleware(cfg.JWTSecret))
-			r.Use(middleware.RequireRole("admin"))
+			staffOnly(r)
+			r.Use(requirePermission(model.ScopeProductsWrite))
 			r.Post("/", productsHandler.Create)
 			r.Put("/{id}", productsHandler.Update)
 			r.Delete("/{id}", productsHandler.Delete)
+			r.Put("/{id}/prices/{currency}", productsHandler.SetPrice)
+			r.Delete("/{id}/prices/{currency}", productsHandler.DeletePrice)
+			r.Put("/{id}/options", productsHandler.SetOptions)
+			r.Post("/{id}/variants", productsHandler.CreateVariant)
+			r.Put("/{id}/variants/{variantID}", productsHandler.UpdateVariant)
+			r.Delete("/{id}/variants/{variantID}", productsHandler.DeleteVariant)
+			r.Post("/{id}/images", productsHandler.UploadImage)
+			r.Put("/{id}/images/order", productsHandler.ReorderImages)
+			r.Patch("/{id}/images/{imageID}", productsHandler.UpdateImage)
+			r.Delete("/{id}/images/{imageID}", productsHandler.DeleteImage)
+		})
+
+		r.Group(func(r chi.Router) {
+			staffOnly(r)
+			r.Use(requirePermission(model.ScopeProductsRead))
+			r.Get("/{id}/prices", productsHandler.ListPrices)
+			r.Get("/{id}/stock/movements", stockHandler.Movements)
+			r.Get("/{id}/variants/{variantID}/stock/movements", stockHandler.Movements)
+		})
+
+		r.Group(func(r chi.Router) {
+			staffOnly(r)
+			r.Use(requirePermission(model.PermissionInventoryManage))
+			r.Post("/{id}/stock/receive", stockHandler.Receive)
+			r.Post("/{id}/stock/adjust", stockHandler.Adjust)
+			r.Post("/{id}/stock/reserve", stockHandler.Reserve)
+			r.Post("/{id}/stock/release", stockHandler.Release)
+			r.Post("/{id}/stock/ship", stockHandler.Ship)
+
+			r.Post("/{id}/variants/{variantID}/stock/receive", stockHandler.Receive)
+			r.Post("/{id}/variants/{variantID}/stock/adjust", stockHandler.Adjust)
+			r.Post("/{id}/variants/{variantID}/stock/reserve", stockHandler.Reserve)
+			r.Post("/{id}/variants/{variantID}/stock/release", stockHandler.Release)
+			r.Post("/{id}/variants/{variantID}/stock/ship", stockHandler.Ship)
 		})
 	})
 
 	return r
 }
+
+func newMailer(cfg config.Config) mail.Mailer {
+	if cfg.MailDriver == "smtp" {
+		return mail.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	}
+	return mail.NewDirMailer(cfg.MailDir, cfg.MailFrom)
+}
+
+func newOIDCProviders(cfg config.Config) map[string]*oidc.Provider {
+	out := map[string]*oidc.Provider{}
+	for _, p := range cfg.OIDCProviders {
+		mappings := make([]oidc.RoleMapping, 0, len(p.RoleMappings))
+		for _, m := range p.RoleMappings {
+			mappings = append(mappings, oidc.RoleMapping{Group: m.Group, Role: m.Role})
+		}
+
+		out[p.Name] = oidc.NewProvider(oidc.Config{
+			Name:         p.Name,
+			DisplayName:  p.DisplayName,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  strings.TrimRight(cfg.APIBaseURL, "/") + "/auth/oidc/" + p.Name + "/callback",
+			Scopes:       p.Scopes,
+			GroupsClaim:  p.GroupsClaim,
+			RoleMappings: mappings,
+			DefaultRole:  p.DefaultRole,
+			AllowSignup:  p.AllowSignup,
+		})
+	}
+	return out
+}
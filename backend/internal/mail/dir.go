@@ -0,0 +1,31 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DirMailer writes every message as an .eml file into a local directory
+// instead of delivering it, for development and offline testing.
+type DirMailer struct {
+	dir  string
+	from string
+}
+
+func NewDirMailer(dir, from string) *DirMailer {
+	return &DirMailer{dir: dir, from: from}
+}
+
+func (m *DirMailer) Send(ctx context.Context, msg Message) error {
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), uuid.NewString())
+	return os.WriteFile(filepath.Join(m.dir, name), msg.bytes(m.from), 0o644)
+}
@@ -0,0 +1,39 @@
+package mail
+
+import (
+	"fmt"
+	"time"
+)
+
+func PasswordResetMessage(to, name, link string, ttl time.Duration) Message {
+	return Message{
+		To:      to,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(`Hi %s,
+
+Someone asked to reset the password for your account. If it was you, open
+the link below to choose a new password:
+
+%s
+
+The link expires in %s and can only be used once. If you did not ask for
+this, you can ignore this email.
+`, name, link, ttl),
+	}
+}
+
+func EmailVerificationMessage(to, name, link string, ttl time.Duration) Message {
+	return Message{
+		To:      to,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf(`Hi %s,
+
+Please confirm that this is your email address by opening the link below:
+
+%s
+
+The link expires in %s. If you did not create an account, you can ignore
+this email.
+`, name, link, ttl),
+	}
+}
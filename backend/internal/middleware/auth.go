@@ -2,17 +2,45 @@ package middleware
 
 import (
 	"context"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
 	"mini-product-catalog/internal/auth"
 	"mini-product-catalog/internal/response"
+	"mini-product-catalog/internal/store"
 	"net/http"
+	"slices"
 	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
 
 	"github.com/google/uuid"
 )
 
+const (
+	PrincipalUser   = "user"
+	PrincipalAPIKey = "api_key"
+)
+
+// CurrentUser is the authenticated principal of a request. For API keys, ID
+// is the key's id, Role is empty and Scopes lists what the key may do.
 type CurrentUser struct {
-	ID   uuid.UUID
-	Role string
+	ID            uuid.UUID
+	Kind          string
+	Role          string
+	Scopes        []string
+	SessionID     string
+	EmailVerified bool
+	MFA           bool
+}
+
+func (u CurrentUser) IsAPIKey() bool {
+	return u.Kind == PrincipalAPIKey
+}
+
+func (u CurrentUser) HasScope(scope string) bool {
+	return slices.Contains(u.Scopes, scope)
 }
 
 type ctxKey int
@@ -25,7 +53,7 @@ func CurrentUserFromContext(ctx context.Context) (CurrentUser, bool) {
 	return u, ok
 }
 
-func AuthMiddleware(jwtSecret string) func(http.Handler) http.Handler {
+func AuthMiddleware(keys *auth.KeySet, apiKeys *store.APIKeyStore, users *store.UserStore, sessions *store.RefreshTokenStore) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			h := r.Header.Get("Authorization")
@@ -35,13 +63,21 @@ func AuthMiddleware(jwtSecret string) func(http.Handler) http.Handler {
 			}
 
 			parts := strings.SplitN(h, " ", 2)
+			if len(parts) == 2 && strings.ToLower(parts[0]) == "apikey" {
+				cur, ok := authenticateAPIKey(w, r, apiKeys, parts[1])
+				if !ok {
+					return
+				}
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), currentUserKey, cur)))
+				return
+			}
 			if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
 				response.WriteError(w, http.StatusUnauthorized, "invalid authorization header", nil)
 				return
 			}
 
 			tokenStr := parts[1]
-			claims, err := auth.ParseAccessToken(tokenStr, jwtSecret)
+			claims, err := auth.ParseAccessToken(keys, tokenStr)
 			if err != nil {
 				response.WriteError(w, http.StatusUnauthorized, "invalid token", nil)
 				return
@@ -53,7 +89,44 @@ func AuthMiddleware(jwtSecret string) func(http.Handler) http.Handler {
 				return
 			}
 
-			cur := CurrentUser{ID: userID, Role: claims.Role}
+			st, err := users.Status(r.Context(), userID)
+			if err != nil {
+				response.WriteError(w, http.StatusInternalServerError, "failed to authenticate", nil)
+				return
+			}
+			if !st.Exists || st.Disabled {
+				response.WriteError(w, http.StatusUnauthorized, "account disabled", nil)
+				return
+			}
+			if claims.TokenVersion != st.TokenVersion {
+				response.WriteError(w, http.StatusUnauthorized, "token revoked", nil)
+				return
+			}
+			if claims.SessionID != "" {
+				familyID, err := uuid.Parse(claims.SessionID)
+				if err != nil {
+					response.WriteError(w, http.StatusUnauthorized, "invalid token session", nil)
+					return
+				}
+				revoked, err := sessions.SessionRevoked(r.Context(), familyID)
+				if err != nil {
+					response.WriteError(w, http.StatusInternalServerError, "failed to authenticate", nil)
+					return
+				}
+				if revoked {
+					response.WriteError(w, http.StatusUnauthorized, "session revoked", nil)
+					return
+				}
+			}
+
+			cur := CurrentUser{
+				ID:            userID,
+				Kind:          PrincipalUser,
+				Role:          claims.Role,
+				SessionID:     claims.SessionID,
+				EmailVerified: claims.EmailVerified,
+				MFA:           claims.HasAMR(auth.AMROTP),
+			}
 			ctx := context.WithValue(r.Context(), currentUserKey, cur)
 
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -61,7 +134,124 @@ func AuthMiddleware(jwtSecret string) func(http.Handler) http.Handler {
 	}
 }
 
-func RequireRole(role string) func(http.Handler) http.Handler {
+// RequirePermission allows users whose role grants perm and API keys that
+// carry it as a scope.
+func RequirePermission(roles *store.RoleStore, perm string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			u, ok := CurrentUserFromContext(r.Context())
+			if !ok {
+				response.WriteError(w, http.StatusUnauthorized, "unauthorized", nil)
+				return
+			}
+
+			allowed := u.HasScope(perm)
+			if !u.IsAPIKey() {
+				perms, err := roles.Permissions(r.Context(), u.Role)
+				if err != nil {
+					response.WriteError(w, http.StatusInternalServerError, "failed to resolve permissions", nil)
+					return
+				}
+				allowed = slices.Contains(perms, perm)
+			}
+			if !allowed {
+				response.WriteError(w, http.StatusForbidden, "forbidden", map[string]string{
+					"permission": perm,
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func RequireVerifiedEmail() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			u, ok := CurrentUserFromContext(r.Context())
+			if !ok {
+				response.WriteError(w, http.StatusUnauthorized, "unauthorized", nil)
+				return
+			}
+			if !u.IsAPIKey() && !u.EmailVerified {
+				response.WriteError(w, http.StatusForbidden, "email not verified", nil)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireMFA rejects users whose role grants any permission when their
+// session was not established with a second factor.
+func RequireMFA(roles *store.RoleStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			u, ok := CurrentUserFromContext(r.Context())
+			if !ok {
+				response.WriteError(w, http.StatusUnauthorized, "unauthorized", nil)
+				return
+			}
+			if u.IsAPIKey() || u.MFA {
+				next.ServeHTTP(w, r)
+				return
+			}
+			staff, err := roles.IsStaff(r.Context(), u.Role)
+			if err != nil {
+				response.WriteError(w, http.StatusInternalServerError, "failed to resolve permissions", nil)
+				return
+			}
+			if staff {
+				response.WriteError(w, http.StatusForbidden, "two-factor authentication required", map[string]string{
+					"setup": "/me/mfa/setup",
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func authenticateAPIKey(w http.ResponseWriter, r *http.Request, apiKeys *store.APIKeyStore, raw string) (CurrentUser, bool) {
+	raw = strings.TrimSpace(raw)
+	prefix, ok := auth.APIKeyPrefix(raw)
+	if !ok {
+		response.WriteError(w, http.StatusUnauthorized, "invalid api key", nil)
+		return CurrentUser{}, false
+	}
+
+	k, err := apiKeys.GetByPrefix(r.Context(), prefix)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.WriteError(w, http.StatusUnauthorized, "invalid api key", nil)
+			return CurrentUser{}, false
+		}
+		response.WriteError(w, http.StatusInternalServerError, "failed to authenticate", nil)
+		return CurrentUser{}, false
+	}
+
+	if subtle.ConstantTimeCompare([]byte(k.KeyHash), []byte(auth.HashToken(raw))) != 1 {
+		response.WriteError(w, http.StatusUnauthorized, "invalid api key", nil)
+		return CurrentUser{}, false
+	}
+	if k.RevokedAt != nil {
+		response.WriteError(w, http.StatusUnauthorized, "api key revoked", nil)
+		return CurrentUser{}, false
+	}
+	if k.ExpiresAt != nil && time.Now().After(*k.ExpiresAt) {
+		response.WriteError(w, http.StatusUnauthorized, "api key expired", nil)
+		return CurrentUser{}, false
+	}
+
+	if err := apiKeys.TouchLastUsed(r.Context(), k.ID); err != nil {
+		slog.Error("failed to update api key last_used_at", "api_key_id", k.ID, "err", err)
+	}
+
+	return CurrentUser{ID: k.ID, Kind: PrincipalAPIKey, Scopes: k.Scopes}, true
+}
+
+// RequireUser rejects requests authenticated with an API key.
+func RequireUser() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			u, ok := CurrentUserFromContext(r.Context())
@@ -69,8 +259,8 @@ func RequireRole(role string) func(http.Handler) http.Handler {
 				response.WriteError(w, http.StatusUnauthorized, "unauthorized", nil)
 				return
 			}
-			if u.Role != role {
-				response.WriteError(w, http.StatusForbidden, "forbidden", nil)
+			if u.IsAPIKey() {
+				response.WriteError(w, http.StatusForbidden, "not available to api keys", nil)
 				return
 			}
 			next.ServeHTTP(w, r)
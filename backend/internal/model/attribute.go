@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AttributeText    = "text"
+	AttributeNumber  = "number"
+	AttributeEnum    = "enum"
+	AttributeBoolean = "boolean"
+)
+
+// CategoryAttribute is one typed field of a category's product schema. Unit
+// only applies to numbers and Options only to enums.
+type CategoryAttribute struct {
+	ID         uuid.UUID `json:"id"`
+	CategoryID uuid.UUID `json:"category_id"`
+	Key        string    `json:"key"`
+	Label      string    `json:"label"`
+	Type       string    `json:"type"`
+	Unit       *string   `json:"unit"`
+	Options    []string  `json:"options"`
+	Required   bool      `json:"required"`
+	Position   int       `json:"position"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type CategoryAttributeRequest struct {
+	Label    string   `json:"label" validate:"required,max=100"`
+	Type     string   `json:"type" validate:"required,oneof=text number enum boolean"`
+	Unit     string   `json:"unit" validate:"max=20"`
+	Options  []string `json:"options" validate:"max=100,dive,required,max=100"`
+	Required bool     `json:"required"`
+	Position int      `json:"position" validate:"gte=0"`
+}
@@ -7,15 +7,56 @@ import (
 )
 
 type Category struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
+	ID          uuid.UUID  `json:"id"`
+	ParentID    *uuid.UUID `json:"parent_id"`
+	Name        string     `json:"name"`
+	Slug        string     `json:"slug"`
+	Description string     `json:"description"`
+	ImageURL    *string    `json:"image_url"`
+	IsVisible   bool       `json:"is_visible"`
+	Position    int        `json:"position"`
+	CreatedAt   time.Time  `json:"created_at"`
 }
 
+// CategoryNode is a category with its subcategories, as returned by the
+// category tree.
+type CategoryNode struct {
+	Category
+	Children []*CategoryNode `json:"children"`
+}
+
+// Breadcrumb is one step of the path from a root category down to a
+// product's category.
+type Breadcrumb struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+// CategoryCreateRequest leaves out the slug to derive it from the name, the
+// position to append the category, and is_visible to show it.
 type CategoryCreateRequest struct {
-	Name string `json:"name" validate:"required,min=2,max=50"`
+	Name        string  `json:"name" validate:"required,min=2,max=50"`
+	ParentID    *string `json:"parent_id" validate:"omitempty,uuid4"`
+	Slug        string  `json:"slug" validate:"max=80"`
+	Description string  `json:"description" validate:"max=2000"`
+	ImageURL    string  `json:"image_url" validate:"omitempty,url,max=500"`
+	IsVisible   *bool   `json:"is_visible"`
+	Position    *int    `json:"position" validate:"omitempty,gte=0"`
 }
 
+// CategoryUpdateRequest replaces the category; leaving parent_id out makes it
+// a root category. An empty slug and omitted is_visible or position keep the
+// current values.
 type CategoryUpdateRequest struct {
-	Name string `json:"name" validate:"required,min=2,max=50"`
+	Name        string  `json:"name" validate:"required,min=2,max=50"`
+	ParentID    *string `json:"parent_id" validate:"omitempty,uuid4"`
+	Slug        string  `json:"slug" validate:"max=80"`
+	Description string  `json:"description" validate:"max=2000"`
+	ImageURL    string  `json:"image_url" validate:"omitempty,url,max=500"`
+	IsVisible   *bool   `json:"is_visible"`
+	Position    *int    `json:"position" validate:"omitempty,gte=0"`
+}
+
+type CategoryOrderRequest struct {
+	IDs []string `json:"ids" validate:"required,min=1,max=500,dive,uuid4"`
 }
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"mini-product-catalog/internal/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const PermissionCurrenciesManage = "currencies:manage"
+
+// ExchangeRate converts BaseCurrency to QuoteCurrency from EffectiveFrom
+// until a later rate for the same pair takes effect.
+type ExchangeRate struct {
+	ID            uuid.UUID  `json:"id"`
+	BaseCurrency  string     `json:"base_currency"`
+	QuoteCurrency string     `json:"quote_currency"`
+	Rate          string     `json:"rate"`
+	EffectiveFrom time.Time  `json:"effective_from"`
+	CreatedBy     *uuid.UUID `json:"created_by"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type ExchangeRateCreateRequest struct {
+	BaseCurrency  string     `json:"base_currency" validate:"required,len=3,nefield=QuoteCurrency"`
+	QuoteCurrency string     `json:"quote_currency" validate:"required,len=3"`
+	Rate          string     `json:"rate" validate:"required"`
+	EffectiveFrom *time.Time `json:"effective_from"`
+}
+
+type RoundingRule struct {
+	Currency  string      `json:"currency"`
+	Increment money.Money `json:"increment"`
+	Mode      string      `json:"mode"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+type RoundingRuleRequest struct {
+	Increment string `json:"increment" validate:"required"`
+	Mode      string `json:"mode" validate:"required,oneof=half_up up down"`
+}
+
+type ProductPrice struct {
+	ProductID uuid.UUID   `json:"product_id"`
+	Price     money.Money `json:"price"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+type ProductPriceRequest struct {
+	Amount string `json:"amount" validate:"required"`
+}
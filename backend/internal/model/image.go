@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProductImage is an uploaded product photo. URL and Thumbnails point at the
+// blob store; the keys behind them are not exposed.
+type ProductImage struct {
+	ID          uuid.UUID         `json:"id"`
+	ProductID   uuid.UUID         `json:"product_id"`
+	URL         string            `json:"url"`
+	Thumbnails  map[string]string `json:"thumbnails"`
+	AltText     string            `json:"alt_text"`
+	Position    int               `json:"position"`
+	IsPrimary   bool              `json:"is_primary"`
+	ContentType string            `json:"content_type"`
+	Width       int               `json:"width"`
+	Height      int               `json:"height"`
+	SizeBytes   int64             `json:"size_bytes"`
+	CreatedAt   time.Time         `json:"created_at"`
+
+	OriginalKey   string            `json:"-"`
+	ThumbnailKeys map[string]string `json:"-"`
+}
+
+type ProductImageUpdateRequest struct {
+	AltText   *string `json:"alt_text" validate:"omitempty,max=300"`
+	IsPrimary *bool   `json:"is_primary"`
+}
+
+type ProductImageOrderRequest struct {
+	IDs []string `json:"ids" validate:"required,min=1,dive,uuid4"`
+}
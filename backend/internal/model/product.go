@@ -1,32 +1,100 @@
 package model
 
 import (
+	"mini-product-catalog/internal/money"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const (
+	PriceSourceOverride  = "override"
+	PriceSourceConverted = "converted"
+)
+
 type Product struct {
-	ID           uuid.UUID `json:"id"`
-	CategoryID   uuid.UUID `json:"category_id"`
-	CategoryName string    `json:"category_name,omitempty"`
-	Name         string    `json:"name"`
-	Description  string    `json:"description"`
-	Price        float64   `json:"price"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID            uuid.UUID   `json:"id"`
+	CategoryID    uuid.UUID   `json:"category_id"`
+	CategoryName  string      `json:"category_name,omitempty"`
+	SKU           string      `json:"sku"`
+	Slug          string      `json:"slug"`
+	Name          string      `json:"name"`
+	Description   string      `json:"description"`
+	Price         money.Money `json:"price"`
+	StockOnHand   int         `json:"stock_on_hand"`
+	StockReserved int         `json:"stock_reserved"`
+	InStock       bool        `json:"in_stock"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+
+	// Attributes holds values for the category's attribute schema, keyed by
+	// attribute key.
+	Attributes map[string]any `json:"attributes"`
+
+	// BasePrice and PriceSource are set when Price was localized to a
+	// requested currency.
+	BasePrice   *money.Money `json:"base_price,omitempty"`
+	PriceSource string       `json:"price_source,omitempty"`
+
+	// Breadcrumbs runs from the root category to CategoryID and is only set
+	// on single-product lookups.
+	Breadcrumbs []Breadcrumb `json:"breadcrumbs,omitempty"`
+
+	// PrimaryImage is set on listings and lookups; Images only on
+	// single-product lookups.
+	PrimaryImage *ProductImage  `json:"primary_image"`
+	Images       []ProductImage `json:"images,omitempty"`
+
+	// PriceRange spans the variants' prices on listings. Options and Variants
+	// are only filled in for single-product lookups.
+	PriceRange *PriceRange      `json:"price_range,omitempty"`
+	Options    []ProductOption  `json:"options,omitempty"`
+	Variants   []ProductVariant `json:"variants,omitempty"`
+
+	Highlights *ProductHighlights `json:"highlights,omitempty"`
+}
+
+// ProductHighlights holds search snippets with matches wrapped in
+// <mark></mark>. The rest of the text is returned as stored, unescaped.
+type ProductHighlights struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
 }
 
 type ProductCreateRequest struct {
-	CategoryID  string  `json:"category_id" validate:"required,uuid4"`
-	Name        string  `json:"name" validate:"required,min=2,max=200"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price" validate:"required,gt=0"`
+	CategoryID  string         `json:"category_id" validate:"required,uuid4"`
+	SKU         string         `json:"sku" validate:"required,max=64"`
+	Name        string         `json:"name" validate:"required,min=2,max=200"`
+	Description string         `json:"description"`
+	Price       money.Money    `json:"price" validate:"required,gt=0"`
+	Attributes  map[string]any `json:"attributes"`
 }
 
 type ProductUpdateRequest struct {
-	CategoryID  string  `json:"category_id" validate:"required,uuid4"`
-	Name        string  `json:"name" validate:"required,min=2,max=200"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price" validate:"required,gt=0"`
+	CategoryID  string         `json:"category_id" validate:"required,uuid4"`
+	SKU         string         `json:"sku" validate:"required,max=64"`
+	Name        string         `json:"name" validate:"required,min=2,max=200"`
+	Description string         `json:"description"`
+	Price       money.Money    `json:"price" validate:"required,gt=0"`
+	Attributes  map[string]any `json:"attributes"`
+}
+
+// ProductFacets holds filter counts for a product listing. Each facet is
+// counted with every other active filter applied but not its own.
+type ProductFacets struct {
+	Category []CategoryFacet `json:"category,omitempty"`
+	Price    []PriceBucket   `json:"price,omitempty"`
+}
+
+type CategoryFacet struct {
+	ID    uuid.UUID `json:"id"`
+	Name  string    `json:"name"`
+	Count int       `json:"count"`
+}
+
+// PriceBucket covers Min <= price < Max. The last bucket has no Max.
+type PriceBucket struct {
+	Min   money.Money  `json:"min"`
+	Max   *money.Money `json:"max"`
+	Count int          `json:"count"`
 }
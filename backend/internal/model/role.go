@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// Permissions checked by the API in addition to the catalog scopes that API
+// keys can carry.
+const (
+	PermissionUsersRead     = "users:read"
+	PermissionUsersWrite    = "users:write"
+	PermissionRolesManage   = "roles:manage"
+	PermissionAPIKeysManage = "api_keys:manage"
+)
+
+type Role struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type Permission struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type RoleCreateRequest struct {
+	Name        string   `json:"name" validate:"required,min=2,max=50"`
+	Description string   `json:"description" validate:"max=200"`
+	Permissions []string `json:"permissions" validate:"dive,required"`
+}
+
+type RoleUpdateRequest struct {
+	Description string   `json:"description" validate:"max=200"`
+	Permissions []string `json:"permissions" validate:"dive,required"`
+}
+
+type UserRoleRequest struct {
+	Role string `json:"role" validate:"required"`
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const PermissionInventoryManage = "inventory:manage"
+
+const (
+	StockReceive = "receive"
+	StockAdjust  = "adjust"
+	StockReserve = "reserve"
+	StockRelease = "release"
+	StockShip    = "ship"
+)
+
+// StockMovement is one entry in the inventory ledger. The deltas are what
+// changed and the after values are the product's, or with VariantID set the
+// variant's, quantities once applied. ProductSKU and VariantSKU are the SKUs
+// at the time, which the ledger keeps after the product or variant is
+// deleted.
+type StockMovement struct {
+	ID            uuid.UUID  `json:"id"`
+	ProductID     uuid.UUID  `json:"product_id"`
+	ProductSKU    string     `json:"product_sku"`
+	VariantID     *uuid.UUID `json:"variant_id"`
+	VariantSKU    *string    `json:"variant_sku"`
+	Kind          string     `json:"kind"`
+	OnHandDelta   int        `json:"on_hand_delta"`
+	ReservedDelta int        `json:"reserved_delta"`
+	OnHandAfter   int        `json:"on_hand_after"`
+	ReservedAfter int        `json:"reserved_after"`
+	Reason        string     `json:"reason"`
+	Reference     *string    `json:"reference"`
+	ActorKind     string     `json:"actor_kind"`
+	ActorID       uuid.UUID  `json:"actor_id"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type StockQuantityRequest struct {
+	Quantity  int    `json:"quantity" validate:"required,gt=0"`
+	Reason    string `json:"reason" validate:"max=500"`
+	Reference string `json:"reference" validate:"max=200"`
+}
+
+type StockAdjustRequest struct {
+	Delta     int    `json:"delta" validate:"required,ne=0"`
+	Reason    string `json:"reason" validate:"required,max=500"`
+	Reference string `json:"reference" validate:"max=200"`
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"mini-product-catalog/internal/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProductOption is an axis such as size or color and the values variants may
+// take on it.
+type ProductOption struct {
+	Name   string   `json:"name" validate:"required,max=50"`
+	Values []string `json:"values" validate:"required,min=1,max=50,dive,required,max=50"`
+}
+
+// ProductVariant is one combination of option values, with its own SKU,
+// price and stock. Prices are in the parent product's currency.
+type ProductVariant struct {
+	ID            uuid.UUID         `json:"id"`
+	ProductID     uuid.UUID         `json:"product_id"`
+	SKU           string            `json:"sku"`
+	Options       map[string]string `json:"options"`
+	Price         money.Money       `json:"price"`
+	StockOnHand   int               `json:"stock_on_hand"`
+	StockReserved int               `json:"stock_reserved"`
+	InStock       bool              `json:"in_stock"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+}
+
+type PriceRange struct {
+	Min money.Money `json:"min"`
+	Max money.Money `json:"max"`
+}
+
+type ProductOptionsRequest struct {
+	Options []ProductOption `json:"options" validate:"max=5,dive"`
+}
+
+type VariantRequest struct {
+	SKU     string            `json:"sku" validate:"required,max=64"`
+	Options map[string]string `json:"options"`
+	Price   money.Money       `json:"price" validate:"required,gt=0"`
+}
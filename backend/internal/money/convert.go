@@ -0,0 +1,74 @@
+package money
+
+import (
+	"fmt"
+	"math/big"
+)
+
+const (
+	RoundHalfUp = "half_up"
+	RoundUp     = "up"
+	RoundDown   = "down"
+)
+
+// Rounding rounds converted amounts to a multiple of Increment minor units,
+// e.g. an increment of 100000 rounds IDR to whole thousands.
+type Rounding struct {
+	Increment int64
+	Mode      string
+}
+
+// Convert turns m into currency to at rate, the number of units of to per
+// unit of m.Currency. The arithmetic is exact up to the final rounding.
+func Convert(m Money, to string, rate *big.Rat, r Rounding) (Money, error) {
+	toExp, ok := exponents[to]
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrUnsupportedCurrency, to)
+	}
+
+	inc := r.Increment
+	if inc < 1 {
+		inc = 1
+	}
+
+	v := new(big.Rat).SetInt64(m.Amount)
+	v.Mul(v, rate)
+	v.Mul(v, pow10(toExp-m.Exp()))
+	v.Quo(v, new(big.Rat).SetInt64(inc))
+
+	n := round(v, r.Mode)
+	n.Mul(n, big.NewInt(inc))
+	if !n.IsInt64() {
+		return Money{}, ErrInvalidAmount
+	}
+
+	return Money{Amount: n.Int64(), Currency: to}, nil
+}
+
+func round(v *big.Rat, mode string) *big.Int {
+	switch mode {
+	case RoundDown:
+		return floor(v)
+	case RoundUp:
+		f := floor(v)
+		if new(big.Rat).SetInt(f).Cmp(v) != 0 {
+			f.Add(f, big.NewInt(1))
+		}
+		return f
+	default:
+		return floor(new(big.Rat).Add(v, big.NewRat(1, 2)))
+	}
+}
+
+func floor(v *big.Rat) *big.Int {
+	// Div is Euclidean and Denom is always positive, so this floors.
+	return new(big.Int).Div(v.Num(), v.Denom())
+}
+
+func pow10(e int) *big.Rat {
+	p := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(e))), nil)
+	if e < 0 {
+		return new(big.Rat).SetFrac(big.NewInt(1), p)
+	}
+	return new(big.Rat).SetInt(p)
+}
@@ -0,0 +1,67 @@
+package money
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	rate := func(s string) *big.Rat {
+		r, ok := new(big.Rat).SetString(s)
+		if !ok {
+			t.Fatalf("bad rate %q", s)
+		}
+		return r
+	}
+
+	tests := []struct {
+		name string
+		m    Money
+		to   string
+		rate string
+		r    Rounding
+		want Money
+	}{
+		{"USD to IDR exact", Money{1000, "USD"}, "IDR", "16250.5", Rounding{}, Money{16250500, "IDR"}},
+		{"IDR thousands half up", Money{1000, "USD"}, "IDR", "16250.5", Rounding{Increment: 100000, Mode: RoundHalfUp}, Money{16300000, "IDR"}},
+		{"IDR thousands down", Money{1000, "USD"}, "IDR", "16250.5", Rounding{Increment: 100000, Mode: RoundDown}, Money{16200000, "IDR"}},
+		{"IDR thousands up", Money{1000, "USD"}, "IDR", "16250.5", Rounding{Increment: 100000, Mode: RoundUp}, Money{16300000, "IDR"}},
+		{"IDR up on exact multiple", Money{1000, "USD"}, "IDR", "16000", Rounding{Increment: 100000, Mode: RoundUp}, Money{16000000, "IDR"}},
+
+		{"USD to JPY half up", Money{123, "USD"}, "JPY", "150.25", Rounding{Mode: RoundHalfUp}, Money{185, "JPY"}},
+		{"USD to JPY down", Money{123, "USD"}, "JPY", "150.25", Rounding{Mode: RoundDown}, Money{184, "JPY"}},
+		{"USD to JPY up", Money{123, "USD"}, "JPY", "150.25", Rounding{Mode: RoundUp}, Money{185, "JPY"}},
+		{"JPY to USD", Money{1000, "JPY"}, "USD", "0.0066666", Rounding{}, Money{667, "USD"}},
+
+		{"USD to KWD", Money{100, "USD"}, "KWD", "0.307", Rounding{}, Money{307, "KWD"}},
+		{"KWD to USD", Money{1, "KWD"}, "USD", "3.25", Rounding{}, Money{0, "USD"}},
+		{"KWD to USD up", Money{1, "KWD"}, "USD", "3.25", Rounding{Mode: RoundUp}, Money{1, "USD"}},
+
+		{"half rounds up", Money{1, "USD"}, "EUR", "0.5", Rounding{Mode: RoundHalfUp}, Money{1, "EUR"}},
+		{"below half rounds down", Money{1, "USD"}, "EUR", "0.49", Rounding{Mode: RoundHalfUp}, Money{0, "EUR"}},
+		{"half down mode", Money{1, "USD"}, "EUR", "0.5", Rounding{Mode: RoundDown}, Money{0, "EUR"}},
+		{"same currency identity", Money{1999, "USD"}, "USD", "1", Rounding{}, Money{1999, "USD"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Convert(tt.m, tt.to, rate(tt.rate), tt.r)
+			if err != nil {
+				t.Fatalf("Convert: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("Convert = %+v (%s), want %+v (%s)", got, got, tt.want, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	if _, err := Convert(Money{100, "USD"}, "XXX", big.NewRat(1, 1), Rounding{}); !errors.Is(err, ErrUnsupportedCurrency) {
+		t.Errorf("unknown target: err = %v, want ErrUnsupportedCurrency", err)
+	}
+	if _, err := Convert(Money{1 << 62, "JPY"}, "IDR", big.NewRat(1000, 1), Rounding{}); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("overflow: err = %v, want ErrInvalidAmount", err)
+	}
+}
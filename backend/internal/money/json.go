@@ -0,0 +1,76 @@
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+type jsonMoney struct {
+	Amount   json.RawMessage `json:"amount"`
+	Currency string          `json:"currency"`
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	if jsonFormat == FormatLegacy {
+		return []byte(m.String()), nil
+	}
+	return json.Marshal(map[string]string{
+		"amount":   m.String(),
+		"currency": m.Currency,
+	})
+}
+
+// UnmarshalJSON accepts an object with amount and currency, or a bare number
+// or decimal string in the default currency. Amounts are read from their
+// literal text, never through float64.
+func (m *Money) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+
+	currency := defaultCurrency
+	if len(b) > 0 && b[0] == '{' {
+		var v jsonMoney
+		if err := json.Unmarshal(b, &v); err != nil {
+			return err
+		}
+		if v.Currency != "" {
+			currency = v.Currency
+		}
+		b = v.Amount
+	}
+
+	amount, err := amountText(b)
+	if err != nil {
+		return err
+	}
+	parsed, err := Parse(amount, currency)
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
+
+func amountText(b []byte) (string, error) {
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return "", err
+		}
+		return s, nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return "", fmt.Errorf("%w: expected a number, string or object", ErrInvalidAmount)
+	}
+	// Exponent notation is valid JSON but not a plain decimal.
+	if bytes.ContainsAny(b, "eE") {
+		return "", fmt.Errorf("%w: exponent notation is not supported", ErrInvalidAmount)
+	}
+	return n.String(), nil
+}
@@ -0,0 +1,174 @@
+// Package money represents prices exactly, as an integer number of minor
+// units (cents, sen, fils) of an ISO 4217 currency.
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const (
+	// FormatObject encodes prices as {"amount": "899000.00", "currency": "IDR"}.
+	FormatObject = "object"
+	// FormatLegacy encodes prices as a bare JSON number, as the API did
+	// before currencies were introduced.
+	FormatLegacy = "legacy"
+)
+
+var (
+	ErrInvalidAmount       = errors.New("invalid amount")
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	ErrTooPrecise          = errors.New("amount has more decimals than the currency allows")
+)
+
+// exponents lists the supported currencies and their number of minor unit
+// digits.
+var exponents = map[string]int{
+	"AUD": 2, "BHD": 3, "CAD": 2, "CHF": 2, "CNY": 2, "EUR": 2, "GBP": 2,
+	"HKD": 2, "IDR": 2, "INR": 2, "JOD": 3, "JPY": 0, "KRW": 0, "KWD": 3,
+	"MYR": 2, "NZD": 2, "OMR": 3, "PHP": 2, "SGD": 2, "THB": 2, "USD": 2,
+	"VND": 0,
+}
+
+var (
+	defaultCurrency = "IDR"
+	jsonFormat      = FormatObject
+)
+
+// Configure sets the currency assumed for amounts given without one and the
+// JSON encoding of Money. It is meant to be called once at startup.
+func Configure(currency, format string) error {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if _, ok := exponents[currency]; !ok {
+		return fmt.Errorf("%w: %q", ErrUnsupportedCurrency, currency)
+	}
+	if format != FormatObject && format != FormatLegacy {
+		return fmt.Errorf("unknown money format %q", format)
+	}
+
+	defaultCurrency = currency
+	jsonFormat = format
+	return nil
+}
+
+func DefaultCurrency() string {
+	return defaultCurrency
+}
+
+// Exponent returns the number of minor unit digits of currency.
+func Exponent(currency string) (int, bool) {
+	e, ok := exponents[currency]
+	return e, ok
+}
+
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// Parse reads a plain decimal such as "899000" or "19.90". Trailing zeros
+// beyond the currency's precision are accepted, other extra digits are not.
+func Parse(amount, currency string) (Money, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	exp, ok := exponents[currency]
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrUnsupportedCurrency, currency)
+	}
+
+	s := strings.TrimSpace(amount)
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" || !isDigits(whole) || !isDigits(frac) || (strings.Contains(s, ".") && frac == "") {
+		return Money{}, ErrInvalidAmount
+	}
+	if len(frac) > exp {
+		if strings.Trim(frac[exp:], "0") != "" {
+			return Money{}, ErrTooPrecise
+		}
+		frac = frac[:exp]
+	}
+	frac += strings.Repeat("0", exp-len(frac))
+
+	n, ok := new(big.Int).SetString(whole+frac, 10)
+	if !ok || !n.IsInt64() {
+		return Money{}, ErrInvalidAmount
+	}
+
+	m := Money{Amount: n.Int64(), Currency: currency}
+	if neg {
+		m.Amount = -m.Amount
+	}
+	return m, nil
+}
+
+// FromDecimal builds Money from unscaled * 10^exp, as databases return
+// NUMERIC values.
+func FromDecimal(unscaled *big.Int, exp int, currency string) (Money, error) {
+	cexp, ok := exponents[currency]
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrUnsupportedCurrency, currency)
+	}
+
+	n := new(big.Int).Set(unscaled)
+	shift := exp + cexp
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(shift))), nil)
+	if shift >= 0 {
+		n.Mul(n, scale)
+	} else {
+		var rem big.Int
+		n.QuoRem(n, scale, &rem)
+		if rem.Sign() != 0 {
+			return Money{}, ErrTooPrecise
+		}
+	}
+	if !n.IsInt64() {
+		return Money{}, ErrInvalidAmount
+	}
+
+	return Money{Amount: n.Int64(), Currency: currency}, nil
+}
+
+// Exp returns the currency's minor unit exponent, so Amount * 10^-Exp() is
+// the value in major units.
+func (m Money) Exp() int {
+	return exponents[m.Currency]
+}
+
+// String formats the amount in major units with the currency's precision,
+// e.g. "899000.00". The currency code is not included.
+func (m Money) String() string {
+	exp := m.Exp()
+	sign := ""
+	n := m.Amount
+	if n < 0 {
+		sign = "-"
+	}
+	digits := new(big.Int).Abs(big.NewInt(n)).String()
+	if exp == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
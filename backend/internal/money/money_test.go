@@ -0,0 +1,124 @@
+package money
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		m    Money
+		want string
+	}{
+		{Money{0, "USD"}, "0.00"},
+		{Money{5, "USD"}, "0.05"},
+		{Money{-5, "USD"}, "-0.05"},
+		{Money{1999, "USD"}, "19.99"},
+		{Money{89900000, "IDR"}, "899000.00"},
+		{Money{-89900000, "IDR"}, "-899000.00"},
+		{Money{0, "JPY"}, "0"},
+		{Money{1500, "JPY"}, "1500"},
+		{Money{-1500, "JPY"}, "-1500"},
+		{Money{1, "KWD"}, "0.001"},
+		{Money{12345, "KWD"}, "12.345"},
+		{Money{1000, "BHD"}, "1.000"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.m.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.m, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		amount, currency string
+		want             Money
+		err              error
+	}{
+		{"19.90", "USD", Money{1990, "USD"}, nil},
+		{"19.9", "USD", Money{1990, "USD"}, nil},
+		{"19", "USD", Money{1900, "USD"}, nil},
+		{"19.900", "USD", Money{1990, "USD"}, nil},
+		{"0.05", "USD", Money{5, "USD"}, nil},
+		{"-0.05", "USD", Money{-5, "USD"}, nil},
+		{" 7 ", "usd", Money{700, "USD"}, nil},
+		{"899000", "IDR", Money{89900000, "IDR"}, nil},
+		{"1500", "JPY", Money{1500, "JPY"}, nil},
+		{"1500.00", "JPY", Money{1500, "JPY"}, nil},
+		{"1.234", "KWD", Money{1234, "KWD"}, nil},
+		{"1.2", "KWD", Money{1200, "KWD"}, nil},
+
+		{"19.901", "USD", Money{}, ErrTooPrecise},
+		{"1500.5", "JPY", Money{}, ErrTooPrecise},
+		{"1.2345", "KWD", Money{}, ErrTooPrecise},
+		{"", "USD", Money{}, ErrInvalidAmount},
+		{".5", "USD", Money{}, ErrInvalidAmount},
+		{"5.", "USD", Money{}, ErrInvalidAmount},
+		{"1e3", "USD", Money{}, ErrInvalidAmount},
+		{"1,50", "USD", Money{}, ErrInvalidAmount},
+		{"--1", "USD", Money{}, ErrInvalidAmount},
+		{"99999999999999999999", "USD", Money{}, ErrInvalidAmount},
+		{"1", "XXX", Money{}, ErrUnsupportedCurrency},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.amount, tt.currency)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Parse(%q, %q) error = %v, want %v", tt.amount, tt.currency, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q, %q) = %+v, want %+v", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestParseStringRoundTrip(t *testing.T) {
+	for currency := range exponents {
+		for _, amount := range []int64{0, 1, 9, 10, 99, 100, 101, 123456789, -1, -1001} {
+			m := Money{amount, currency}
+			got, err := Parse(m.String(), currency)
+			if err != nil || got != m {
+				t.Errorf("Parse(%q, %q) = %+v, %v; want %+v", m.String(), currency, got, err, m)
+			}
+		}
+	}
+}
+
+func TestFromDecimal(t *testing.T) {
+	tests := []struct {
+		unscaled int64
+		exp      int
+		currency string
+		want     Money
+		err      error
+	}{
+		{1999, -2, "USD", Money{1999, "USD"}, nil},
+		{19990, -3, "USD", Money{1999, "USD"}, nil},
+		{19, 0, "USD", Money{1900, "USD"}, nil},
+		{15, 2, "JPY", Money{1500, "JPY"}, nil},
+		{15000, -1, "JPY", Money{1500, "JPY"}, nil},
+		{1, 0, "KWD", Money{1000, "KWD"}, nil},
+		{1234, -3, "KWD", Money{1234, "KWD"}, nil},
+		{-899000000, -3, "IDR", Money{-89900000, "IDR"}, nil},
+
+		{19991, -3, "USD", Money{}, ErrTooPrecise},
+		{15001, -1, "JPY", Money{}, ErrTooPrecise},
+		{1, 30, "USD", Money{}, ErrInvalidAmount},
+		{1, 0, "XXX", Money{}, ErrUnsupportedCurrency},
+	}
+
+	for _, tt := range tests {
+		got, err := FromDecimal(big.NewInt(tt.unscaled), tt.exp, tt.currency)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("FromDecimal(%d, %d, %q) error = %v, want %v", tt.unscaled, tt.exp, tt.currency, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("FromDecimal(%d, %d, %q) = %+v, want %+v", tt.unscaled, tt.exp, tt.currency, got, tt.want)
+		}
+	}
+}
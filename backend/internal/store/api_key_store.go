@@ -0,0 +1,95 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"mini-product-catalog/internal/model"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type APIKeyStore struct {
+	db *pgxpool.Pool
+}
+
+func NewAPIKeyStore(db *pgxpool.Pool) *APIKeyStore {
+	return &APIKeyStore{db: db}
+}
+
+const apiKeyColumns = `id, name, prefix, key_hash, scopes, created_by, expires_at, last_used_at, revoked_at, created_at`
+
+func scanAPIKey(row pgx.Row) (model.APIKey, error) {
+	var k model.APIKey
+	err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.KeyHash, &k.Scopes, &k.CreatedBy, &k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt, &k.CreatedAt)
+	return k, err
+}
+
+func (s *APIKeyStore) Create(ctx context.Context, name, prefix, keyHash string, scopes []string, createdBy uuid.UUID, expiresAt *time.Time) (model.APIKey, error) {
+	return scanAPIKey(s.db.QueryRow(ctx, `
+		INSERT INTO api_keys (name, prefix, key_hash, scopes, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+apiKeyColumns,
+		name, prefix, keyHash, scopes, createdBy, expiresAt))
+}
+
+func (s *APIKeyStore) List(ctx context.Context) ([]model.APIKey, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		ORDER BY created_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []model.APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (s *APIKeyStore) GetByPrefix(ctx context.Context, prefix string) (model.APIKey, error) {
+	k, err := scanAPIKey(s.db.QueryRow(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		WHERE prefix = $1
+	`, prefix))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.APIKey{}, pgx.ErrNoRows
+	}
+	return k, err
+}
+
+func (s *APIKeyStore) Revoke(ctx context.Context, id uuid.UUID) (model.APIKey, error) {
+	return scanAPIKey(s.db.QueryRow(ctx, `
+		UPDATE api_keys
+		SET revoked_at = COALESCE(revoked_at, now())
+		WHERE id = $1
+		RETURNING `+apiKeyColumns,
+		id))
+}
+
+// TouchLastUsed records usage at most once a minute per key to avoid a write
+// on every request.
+func (s *APIKeyStore) TouchLastUsed(ctx context.Context, id uuid.UUID) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE api_keys
+		SET last_used_at = now()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')
+	`, id)
+	return err
+}
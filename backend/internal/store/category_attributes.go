@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"mini-product-catalog/internal/model"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrAttributeInUse means a schema change would invalidate values products
+// already hold.
+var ErrAttributeInUse = errors.New("attribute values are in use")
+
+const categoryAttributeColumns = `id, category_id, key, label, type, unit, options, required, position, created_at`
+
+func scanCategoryAttribute(row pgx.Row) (model.CategoryAttribute, error) {
+	var a model.CategoryAttribute
+	err := row.Scan(&a.ID, &a.CategoryID, &a.Key, &a.Label, &a.Type, &a.Unit, &a.Options, &a.Required, &a.Position, &a.CreatedAt)
+	return a, err
+}
+
+func (s *CategoryStore) ListAttributes(ctx context.Context, categoryID uuid.UUID) ([]model.CategoryAttribute, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT `+categoryAttributeColumns+`
+		FROM category_attributes
+		WHERE category_id = $1
+		ORDER BY position ASC, key ASC
+	`, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []model.CategoryAttribute{}
+	for rows.Next() {
+		a, err := scanCategoryAttribute(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// SetAttribute creates or replaces an attribute. Changing the type, or
+// dropping enum options, fails with ErrAttributeInUse while products of the
+// category still hold values that wouldn't fit.
+func (s *CategoryStore) SetAttribute(ctx context.Context, a model.CategoryAttribute) (model.CategoryAttribute, error) {
+	var out model.CategoryAttribute
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		old, err := scanCategoryAttribute(tx.QueryRow(ctx, `
+			SELECT `+categoryAttributeColumns+`
+			FROM category_attributes
+			WHERE category_id = $1 AND key = $2
+			FOR UPDATE
+		`, a.CategoryID, a.Key))
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+		case err != nil:
+			return err
+		case old.Type != a.Type:
+			if err := attributeUnused(ctx, tx, a.CategoryID, a.Key, nil); err != nil {
+				return err
+			}
+		case a.Type == model.AttributeEnum:
+			var removed []string
+			for _, o := range old.Options {
+				if !slices.Contains(a.Options, o) {
+					removed = append(removed, o)
+				}
+			}
+			if len(removed) > 0 {
+				if err := attributeUnused(ctx, tx, a.CategoryID, a.Key, removed); err != nil {
+					return err
+				}
+			}
+		}
+
+		out, err = scanCategoryAttribute(tx.QueryRow(ctx, `
+			INSERT INTO category_attributes (category_id, key, label, type, unit, options, required, position)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (category_id, key) DO UPDATE
+			SET label = EXCLUDED.label,
+				type = EXCLUDED.type,
+				unit = EXCLUDED.unit,
+				options = EXCLUDED.options,
+				required = EXCLUDED.required,
+				position = EXCLUDED.position
+			RETURNING `+categoryAttributeColumns,
+			a.CategoryID, a.Key, a.Label, a.Type, a.Unit, a.Options, a.Required, a.Position))
+		return err
+	})
+
+	return out, err
+}
+
+// attributeUnused fails with ErrAttributeInUse if a product in the category
+// has the attribute set, or set to one of values when values is non-nil.
+func attributeUnused(ctx context.Context, tx pgx.Tx, categoryID uuid.UUID, key string, values []string) error {
+	var used bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM products
+			WHERE category_id = $1
+				AND attributes ? $2
+				AND ($3::text[] IS NULL OR attributes->>$2 = ANY($3))
+		)
+	`, categoryID, key, values).Scan(&used)
+	if err != nil {
+		return err
+	}
+	if used {
+		return ErrAttributeInUse
+	}
+	return nil
+}
+
+// DeleteAttribute removes an attribute and strips its values from the
+// category's products.
+func (s *CategoryStore) DeleteAttribute(ctx context.Context, categoryID uuid.UUID, key string) (bool, error) {
+	var ok bool
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		ct, err := tx.Exec(ctx, `DELETE FROM category_attributes WHERE category_id = $1 AND key = $2`, categoryID, key)
+		if err != nil {
+			return err
+		}
+		if ok = ct.RowsAffected() > 0; !ok {
+			return nil
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE products
+			SET attributes = attributes - $2, updated_at = now()
+			WHERE category_id = $1 AND attributes ? $2
+		`, categoryID, key)
+		return err
+	})
+
+	return ok, err
+}
@@ -2,13 +2,26 @@ package store
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"mini-product-catalog/internal/model"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrCategoryCycle means a category would become its own ancestor.
+var ErrCategoryCycle = errors.New("category cannot be moved below itself")
+
+// ErrCategoryOrder means a reorder named an unknown category or one twice.
+var ErrCategoryOrder = errors.New("ids must be distinct existing categories")
+
+// ErrCategorySlugTaken means an explicitly chosen slug belongs to another
+// category.
+var ErrCategorySlugTaken = errors.New("category slug already in use")
+
 type CategoryStore struct {
 	db *pgxpool.Pool
 }
@@ -17,12 +30,45 @@ func NewCategoryStore(db *pgxpool.Pool) *CategoryStore {
 	return &CategoryStore{db: db}
 }
 
-func (s *CategoryStore) List(ctx context.Context) ([]model.Category, error) {
+// CategoryFields are the writable parts of a category. An empty Slug and nil
+// IsVisible or Position mean "derive or keep": a slug from the name, visible,
+// and the end of the list on create; the current values on update.
+type CategoryFields struct {
+	ParentID    *uuid.UUID
+	Name        string
+	Slug        string
+	Description string
+	ImageURL    *string
+	IsVisible   *bool
+	Position    *int
+}
+
+const categoryColumns = `id, parent_id, name, slug, description, image_url, is_visible, position, created_at`
+
+func scanCategory(row pgx.Row) (model.Category, error) {
+	var c model.Category
+	err := row.Scan(&c.ID, &c.ParentID, &c.Name, &c.Slug, &c.Description, &c.ImageURL, &c.IsVisible, &c.Position, &c.CreatedAt)
+	return c, err
+}
+
+// visibleCategoryIDs selects the categories the storefront shows: those
+// visible themselves and through every ancestor. Product listings and
+// lookups are limited to them as well.
+const visibleCategoryIDs = `WITH RECURSIVE shown AS (
+	SELECT id FROM categories WHERE parent_id IS NULL AND is_visible
+	UNION ALL
+	SELECT c.id FROM categories c JOIN shown s ON c.parent_id = s.id WHERE c.is_visible
+) SELECT id FROM shown`
+
+// List returns categories in display order. Hidden ones, and everything
+// below them, are left out unless includeHidden is set.
+func (s *CategoryStore) List(ctx context.Context, includeHidden bool) ([]model.Category, error) {
 	rows, err := s.db.Query(ctx, `
-		SELECT id, name, created_at
+		SELECT `+categoryColumns+`
 		FROM categories
-		ORDER BY created_at DESC
-	`)
+		WHERE $1 OR id IN (`+visibleCategoryIDs+`)
+		ORDER BY position ASC, name ASC
+	`, includeHidden)
 
 	if err != nil {
 		return nil, err
@@ -31,8 +77,8 @@ func (s *CategoryStore) List(ctx context.Context) ([]model.Category, error) {
 
 	out := []model.Category{}
 	for rows.Next() {
-		var c model.Category
-		if err := rows.Scan(&c.ID, &c.Name, &c.CreatedAt); err != nil {
+		c, err := scanCategory(rows)
+		if err != nil {
 			return nil, err
 		}
 		out = append(out, c)
@@ -44,15 +90,79 @@ func (s *CategoryStore) List(ctx context.Context) ([]model.Category, error) {
 	return out, nil
 }
 
-func (s *CategoryStore) Create(ctx context.Context, name string) (model.Category, error) {
+// GetBySlug finds a category by slug. Without includeHidden, categories List
+// would leave out are pgx.ErrNoRows.
+func (s *CategoryStore) GetBySlug(ctx context.Context, slug string, includeHidden bool) (model.Category, error) {
+	return scanCategory(s.db.QueryRow(ctx, `
+		SELECT `+categoryColumns+`
+		FROM categories
+		WHERE slug = $2 AND ($1 OR id IN (`+visibleCategoryIDs+`))
+	`, includeHidden, slug))
+}
+
+func (s *CategoryStore) Create(ctx context.Context, f CategoryFields) (model.Category, error) {
 	var c model.Category
-	err := s.db.QueryRow(ctx, `
-		INSERT INTO categories (name)
-		VALUES ($1)
-		RETURNING id, name, created_at
-	`, name).Scan(&c.ID, &c.Name, &c.CreatedAt)
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		slug := f.Slug
+		if slug == "" {
+			var err error
+			if slug, err = availableCategorySlug(ctx, tx, slugify(f.Name, "category")); err != nil {
+				return err
+			}
+		}
 
-	return c, err
+		var err error
+		c, err = scanCategory(tx.QueryRow(ctx, `
+			INSERT INTO categories (parent_id, name, slug, description, image_url, is_visible, position)
+			VALUES ($1, $2, $3, $4, $5, COALESCE($6, true),
+				COALESCE($7, (SELECT COALESCE(MAX(position) + 1, 0) FROM categories)))
+			RETURNING `+categoryColumns,
+			f.ParentID, f.Name, slug, f.Description, f.ImageURL, f.IsVisible, f.Position))
+		return err
+	})
+
+	return c, slugTaken(err)
+}
+
+// availableCategorySlug returns base or the first free base-N.
+func availableCategorySlug(ctx context.Context, tx pgx.Tx, base string) (string, error) {
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('category_slug:' || $1))`, base); err != nil {
+		return "", err
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT slug FROM categories
+		WHERE slug = $1 OR slug LIKE $1 || '-%'
+	`, base)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	used := map[string]bool{}
+	for rows.Next() {
+		var t string
+		if err := rows.Scan(&t); err != nil {
+			return "", err
+		}
+		used[t] = true
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	slug := base
+	for n := 2; used[slug]; n++ {
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+	return slug, nil
+}
+
+func slugTaken(err error) error {
+	if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.ConstraintName == "categories_slug_key" {
+		return ErrCategorySlugTaken
+	}
+	return err
 }
 
 func IsUniqueViolation(err error) bool {
@@ -71,33 +181,157 @@ func (s *CategoryStore) Exists(ctx context.Context, id uuid.UUID) (bool, error)
 	return ok, err
 }
 
-func (s *CategoryStore) Update(ctx context.Context, id uuid.UUID, name string) (model.Category, error) {
+// Update renames and moves a category. Moves are serialized so two
+// concurrent moves can't together form a cycle.
+func (s *CategoryStore) Update(ctx context.Context, id uuid.UUID, f CategoryFields) (model.Category, error) {
 	var c model.Category
-	err := s.db.QueryRow(ctx, `
-		UPDATE categories
-		SET name = $2
-		WHERE id = $1
-		RETURNING id, name, created_at
-	`, id, name).Scan(&c.ID, &c.Name, &c.CreatedAt)
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if f.ParentID != nil {
+			if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('category_tree'))`); err != nil {
+				return err
+			}
 
-	if err != nil {
-		return model.Category{}, err
-	}
+			// Walking up from the new parent must not reach the category.
+			var cycle bool
+			err := tx.QueryRow(ctx, `
+				WITH RECURSIVE ancestors AS (
+					SELECT id, parent_id FROM categories WHERE id = $2
+					UNION
+					SELECT c.id, c.parent_id FROM categories c JOIN ancestors a ON c.id = a.parent_id
+				)
+				SELECT EXISTS(SELECT 1 FROM ancestors WHERE id = $1)
+			`, id, *f.ParentID).Scan(&cycle)
+			if err != nil {
+				return err
+			}
+			if cycle {
+				return ErrCategoryCycle
+			}
+		}
+
+		var err error
+		c, err = scanCategory(tx.QueryRow(ctx, `
+			UPDATE categories
+			SET parent_id = $2,
+				name = $3,
+				slug = COALESCE(NULLIF($4, ''), slug),
+				description = $5,
+				image_url = $6,
+				is_visible = COALESCE($7, is_visible),
+				position = COALESCE($8, position)
+			WHERE id = $1
+			RETURNING `+categoryColumns,
+			id, f.ParentID, f.Name, f.Slug, f.Description, f.ImageURL, f.IsVisible, f.Position))
+		return err
+	})
 
-	return c, nil
+	return c, slugTaken(err)
 }
 
 func (s *CategoryStore) Delete(ctx context.Context, id uuid.UUID) (model.Category, error) {
-	var c model.Category
-	err := s.db.QueryRow(ctx, `
+	return scanCategory(s.db.QueryRow(ctx, `
 		DELETE FROM categories
 		WHERE id = $1
-		RETURNING id, name, created_at
-	`, id).Scan(&c.ID, &c.Name, &c.CreatedAt)
+		RETURNING `+categoryColumns,
+		id))
+}
 
+// Tree returns the root categories with their subcategories nested below,
+// each level in display order. Without includeHidden, hidden categories are
+// left out together with everything below them.
+func (s *CategoryStore) Tree(ctx context.Context, includeHidden bool) ([]*model.CategoryNode, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT `+categoryColumns+`
+		FROM categories
+		WHERE $1 OR id IN (`+visibleCategoryIDs+`)
+		ORDER BY position ASC, name ASC
+	`, includeHidden)
 	if err != nil {
-		return model.Category{}, err
+		return nil, err
 	}
+	defer rows.Close()
 
-	return c, nil
+	var all []*model.CategoryNode
+	byID := map[uuid.UUID]*model.CategoryNode{}
+	for rows.Next() {
+		c, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		n := &model.CategoryNode{Category: c, Children: []*model.CategoryNode{}}
+		all = append(all, n)
+		byID[c.ID] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	roots := []*model.CategoryNode{}
+	for _, n := range all {
+		if n.ParentID == nil {
+			roots = append(roots, n)
+			continue
+		}
+		if parent, ok := byID[*n.ParentID]; ok {
+			parent.Children = append(parent.Children, n)
+		}
+	}
+	return roots, nil
+}
+
+// Breadcrumbs returns the path from the root category down to id, and
+// whether every category on it is visible.
+func (s *CategoryStore) Breadcrumbs(ctx context.Context, id uuid.UUID) ([]model.Breadcrumb, bool, error) {
+	rows, err := s.db.Query(ctx, `
+		WITH RECURSIVE path AS (
+			SELECT id, parent_id, name, is_visible, 0 AS depth FROM categories WHERE id = $1
+			UNION ALL
+			SELECT c.id, c.parent_id, c.name, c.is_visible, p.depth + 1
+			FROM categories c JOIN path p ON c.id = p.parent_id
+			WHERE p.depth < 100
+		)
+		SELECT id, name, is_visible FROM path ORDER BY depth DESC
+	`, id)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	out := []model.Breadcrumb{}
+	shown := true
+	for rows.Next() {
+		var b model.Breadcrumb
+		var visible bool
+		if err := rows.Scan(&b.ID, &b.Name, &visible); err != nil {
+			return nil, false, err
+		}
+		out = append(out, b)
+		shown = shown && visible
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	return out, shown, nil
+}
+
+// Reorder sets the positions of the given categories to 0, 1, 2... in the
+// order listed, all or nothing. Categories not listed keep their positions,
+// so callers normally send a complete list of siblings.
+func (s *CategoryStore) Reorder(ctx context.Context, ids []uuid.UUID) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		ct, err := tx.Exec(ctx, `
+			UPDATE categories c
+			SET position = o.n - 1
+			FROM unnest($1::uuid[]) WITH ORDINALITY AS o(id, n)
+			WHERE c.id = o.id
+		`, ids)
+		if err != nil {
+			return err
+		}
+		if int(ct.RowsAffected()) != len(ids) {
+			return ErrCategoryOrder
+		}
+		return nil
+	})
 }
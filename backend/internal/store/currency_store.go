@@ -0,0 +1,269 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"mini-product-catalog/internal/model"
+	"mini-product-catalog/internal/money"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrNoExchangeRate = errors.New("no exchange rate")
+
+type CurrencyStore struct {
+	db *pgxpool.Pool
+}
+
+func NewCurrencyStore(db *pgxpool.Pool) *CurrencyStore {
+	return &CurrencyStore{db: db}
+}
+
+const exchangeRateColumns = `id, base_currency, quote_currency, rate::text, effective_from, created_by, created_at`
+
+func scanExchangeRate(row pgx.Row) (model.ExchangeRate, error) {
+	var r model.ExchangeRate
+	err := row.Scan(&r.ID, &r.BaseCurrency, &r.QuoteCurrency, &r.Rate, &r.EffectiveFrom, &r.CreatedBy, &r.CreatedAt)
+	return r, err
+}
+
+func (s *CurrencyStore) ListRates(ctx context.Context, base, quote string) ([]model.ExchangeRate, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT `+exchangeRateColumns+`
+		FROM exchange_rates
+		WHERE ($1 = '' OR base_currency = $1)
+			AND ($2 = '' OR quote_currency = $2)
+		ORDER BY base_currency, quote_currency, effective_from DESC
+	`, base, quote)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []model.ExchangeRate{}
+	for rows.Next() {
+		r, err := scanExchangeRate(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (s *CurrencyStore) CreateRate(ctx context.Context, base, quote, rate string, effectiveFrom time.Time, createdBy uuid.UUID) (model.ExchangeRate, error) {
+	return scanExchangeRate(s.db.QueryRow(ctx, `
+		INSERT INTO exchange_rates (base_currency, quote_currency, rate, effective_from, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+exchangeRateColumns,
+		base, quote, rate, effectiveFrom, createdBy))
+}
+
+func (s *CurrencyStore) DeleteRate(ctx context.Context, id uuid.UUID) (bool, error) {
+	tag, err := s.db.Exec(ctx, `DELETE FROM exchange_rates WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// Rate returns how many units of quote one unit of base buys at the given
+// time. A rate entered for the opposite direction is inverted when there is
+// none for this one.
+func (s *CurrencyStore) Rate(ctx context.Context, base, quote string, at time.Time) (*big.Rat, error) {
+	var rate string
+	var inverse bool
+	err := s.db.QueryRow(ctx, `
+		SELECT rate::text, base_currency <> $1
+		FROM exchange_rates
+		WHERE ((base_currency = $1 AND quote_currency = $2) OR (base_currency = $2 AND quote_currency = $1))
+			AND effective_from <= $3
+		ORDER BY base_currency <> $1, effective_from DESC
+		LIMIT 1
+	`, base, quote, at).Scan(&rate, &inverse)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNoExchangeRate
+		}
+		return nil, err
+	}
+
+	r, ok := new(big.Rat).SetString(rate)
+	if !ok || r.Sign() <= 0 {
+		return nil, ErrNoExchangeRate
+	}
+	if inverse {
+		r.Inv(r)
+	}
+	return r, nil
+}
+
+func scanRoundingRule(row pgx.Row) (model.RoundingRule, error) {
+	var r model.RoundingRule
+	if err := row.Scan(&r.Currency, &r.Increment.Amount, &r.Mode, &r.UpdatedAt); err != nil {
+		return model.RoundingRule{}, err
+	}
+	r.Increment.Currency = r.Currency
+	return r, nil
+}
+
+func (s *CurrencyStore) ListRoundingRules(ctx context.Context) ([]model.RoundingRule, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT currency, increment_minor, mode, updated_at
+		FROM currency_rounding_rules
+		ORDER BY currency
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []model.RoundingRule{}
+	for rows.Next() {
+		r, err := scanRoundingRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (s *CurrencyStore) SetRoundingRule(ctx context.Context, increment money.Money, mode string) (model.RoundingRule, error) {
+	return scanRoundingRule(s.db.QueryRow(ctx, `
+		INSERT INTO currency_rounding_rules (currency, increment_minor, mode)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (currency) DO UPDATE
+		SET increment_minor = EXCLUDED.increment_minor,
+			mode = EXCLUDED.mode,
+			updated_at = now()
+		RETURNING currency, increment_minor, mode, updated_at
+	`, increment.Currency, increment.Amount, mode))
+}
+
+func (s *CurrencyStore) DeleteRoundingRule(ctx context.Context, currency string) (bool, error) {
+	tag, err := s.db.Exec(ctx, `DELETE FROM currency_rounding_rules WHERE currency = $1`, currency)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// Rounding returns the rule for currency, defaulting to half-up on the
+// smallest minor unit.
+func (s *CurrencyStore) Rounding(ctx context.Context, currency string) (money.Rounding, error) {
+	r := money.Rounding{Increment: 1, Mode: money.RoundHalfUp}
+	err := s.db.QueryRow(ctx, `
+		SELECT increment_minor, mode FROM currency_rounding_rules WHERE currency = $1
+	`, currency).Scan(&r.Increment, &r.Mode)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return money.Rounding{}, err
+	}
+	return r, nil
+}
+
+func scanProductPrice(row pgx.Row) (model.ProductPrice, error) {
+	var p model.ProductPrice
+	var price pgtype.Numeric
+	var currency string
+	if err := row.Scan(&p.ProductID, &price, &currency, &p.UpdatedAt); err != nil {
+		return model.ProductPrice{}, err
+	}
+
+	var err error
+	p.Price, err = moneyFromNumeric(price, currency)
+	if err != nil {
+		return model.ProductPrice{}, err
+	}
+	return p, nil
+}
+
+func (s *CurrencyStore) ListProductPrices(ctx context.Context, productID uuid.UUID) ([]model.ProductPrice, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT product_id, price, currency, updated_at
+		FROM product_prices
+		WHERE product_id = $1
+		ORDER BY currency
+	`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []model.ProductPrice{}
+	for rows.Next() {
+		p, err := scanProductPrice(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// PriceOverrides returns the override prices in currency for whichever of
+// productIDs have one.
+func (s *CurrencyStore) PriceOverrides(ctx context.Context, productIDs []uuid.UUID, currency string) (map[uuid.UUID]money.Money, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT product_id, price, currency, updated_at
+		FROM product_prices
+		WHERE product_id = ANY($1) AND currency = $2
+	`, productIDs, currency)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[uuid.UUID]money.Money{}
+	for rows.Next() {
+		p, err := scanProductPrice(rows)
+		if err != nil {
+			return nil, err
+		}
+		out[p.ProductID] = p.Price
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (s *CurrencyStore) SetProductPrice(ctx context.Context, productID uuid.UUID, price money.Money) (model.ProductPrice, error) {
+	return scanProductPrice(s.db.QueryRow(ctx, `
+		INSERT INTO product_prices (product_id, currency, price)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (product_id, currency) DO UPDATE
+		SET price = EXCLUDED.price,
+			updated_at = now()
+		RETURNING product_id, price, currency, updated_at
+	`, productID, price.Currency, numeric(price)))
+}
+
+func (s *CurrencyStore) DeleteProductPrice(ctx context.Context, productID uuid.UUID, currency string) (bool, error) {
+	tag, err := s.db.Exec(ctx, `DELETE FROM product_prices WHERE product_id = $1 AND currency = $2`, productID, currency)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
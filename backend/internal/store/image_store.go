@@ -0,0 +1,207 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"mini-product-catalog/internal/model"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrImageOrder means a reorder didn't list exactly the product's images.
+var ErrImageOrder = errors.New("ids must list every image of the product once")
+
+type ImageStore struct {
+	db *pgxpool.Pool
+}
+
+func NewImageStore(db *pgxpool.Pool) *ImageStore {
+	return &ImageStore{db: db}
+}
+
+const productImageColumns = `id, product_id, position, alt_text, is_primary, content_type, width, height, size_bytes, original_key, thumbnail_keys, created_at`
+
+func scanProductImage(row pgx.Row) (model.ProductImage, error) {
+	var i model.ProductImage
+	err := row.Scan(&i.ID, &i.ProductID, &i.Position, &i.AltText, &i.IsPrimary, &i.ContentType, &i.Width, &i.Height, &i.SizeBytes, &i.OriginalKey, &i.ThumbnailKeys, &i.CreatedAt)
+	return i, err
+}
+
+func (s *ImageStore) List(ctx context.Context, productID uuid.UUID) ([]model.ProductImage, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT `+productImageColumns+`
+		FROM product_images
+		WHERE product_id = $1
+		ORDER BY position ASC, created_at ASC
+	`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []model.ProductImage{}
+	for rows.Next() {
+		i, err := scanProductImage(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// Primary returns the primary image of each product that has one.
+func (s *ImageStore) Primary(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID]model.ProductImage, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT `+productImageColumns+`
+		FROM product_images
+		WHERE product_id = ANY($1) AND is_primary
+	`, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[uuid.UUID]model.ProductImage{}
+	for rows.Next() {
+		i, err := scanProductImage(rows)
+		if err != nil {
+			return nil, err
+		}
+		out[i.ProductID] = i
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// Create appends an image to the product's gallery. The first image becomes
+// the primary one. A missing product is pgx.ErrNoRows.
+func (s *ImageStore) Create(ctx context.Context, img model.ProductImage) (model.ProductImage, error) {
+	var out model.ProductImage
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		var exists int
+		if err := tx.QueryRow(ctx, `SELECT 1 FROM products WHERE id = $1 FOR UPDATE`, img.ProductID).Scan(&exists); err != nil {
+			return err
+		}
+
+		var err error
+		out, err = scanProductImage(tx.QueryRow(ctx, `
+			INSERT INTO product_images
+				(id, product_id, position, alt_text, is_primary, content_type, width, height, size_bytes, original_key, thumbnail_keys)
+			SELECT $1, $2,
+				COALESCE(MAX(position) + 1, 0),
+				$3,
+				NOT COALESCE(bool_or(is_primary), false),
+				$4, $5, $6, $7, $8, $9
+			FROM product_images
+			WHERE product_id = $2
+			RETURNING `+productImageColumns,
+			img.ID, img.ProductID, img.AltText, img.ContentType, img.Width, img.Height, img.SizeBytes, img.OriginalKey, img.ThumbnailKeys))
+		return err
+	})
+
+	return out, err
+}
+
+// Update changes the alt text and, when makePrimary is set, moves the
+// primary flag to this image.
+func (s *ImageStore) Update(ctx context.Context, productID, id uuid.UUID, altText *string, makePrimary bool) (model.ProductImage, error) {
+	var out model.ProductImage
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		var exists int
+		if err := tx.QueryRow(ctx, `SELECT 1 FROM product_images WHERE id = $1 AND product_id = $2 FOR UPDATE`, id, productID).Scan(&exists); err != nil {
+			return err
+		}
+
+		if makePrimary {
+			_, err := tx.Exec(ctx, `
+				UPDATE product_images SET is_primary = false
+				WHERE product_id = $1 AND is_primary AND id <> $2
+			`, productID, id)
+			if err != nil {
+				return err
+			}
+		}
+
+		var err error
+		out, err = scanProductImage(tx.QueryRow(ctx, `
+			UPDATE product_images
+			SET alt_text = COALESCE($3, alt_text),
+				is_primary = is_primary OR $4
+			WHERE id = $1 AND product_id = $2
+			RETURNING `+productImageColumns,
+			id, productID, altText, makePrimary))
+		return err
+	})
+
+	return out, err
+}
+
+// Reorder sets positions to follow ids, which must name each of the
+// product's images exactly once.
+func (s *ImageStore) Reorder(ctx context.Context, productID uuid.UUID, ids []uuid.UUID) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		var exists int
+		if err := tx.QueryRow(ctx, `SELECT 1 FROM products WHERE id = $1 FOR UPDATE`, productID).Scan(&exists); err != nil {
+			return err
+		}
+
+		ct, err := tx.Exec(ctx, `
+			UPDATE product_images i
+			SET position = o.position - 1
+			FROM unnest($2::uuid[]) WITH ORDINALITY AS o(id, position)
+			WHERE i.id = o.id AND i.product_id = $1
+		`, productID, ids)
+		if err != nil {
+			return err
+		}
+
+		var total int
+		if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM product_images WHERE product_id = $1`, productID).Scan(&total); err != nil {
+			return err
+		}
+		if int(ct.RowsAffected()) != len(ids) || total != len(ids) {
+			return ErrImageOrder
+		}
+		return nil
+	})
+}
+
+// Delete removes an image and returns it so its blobs can be cleaned up. If
+// it was the primary image, the next one in order takes over.
+func (s *ImageStore) Delete(ctx context.Context, productID, id uuid.UUID) (model.ProductImage, error) {
+	var out model.ProductImage
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		var err error
+		out, err = scanProductImage(tx.QueryRow(ctx, `
+			DELETE FROM product_images
+			WHERE id = $1 AND product_id = $2
+			RETURNING `+productImageColumns,
+			id, productID))
+		if err != nil || !out.IsPrimary {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE product_images SET is_primary = true
+			WHERE id = (
+				SELECT id FROM product_images
+				WHERE product_id = $1
+				ORDER BY position ASC, created_at ASC
+				LIMIT 1
+			)
+		`, productID)
+		return err
+	})
+
+	return out, err
+}
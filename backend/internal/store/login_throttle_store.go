@@ -0,0 +1,63 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type LoginThrottleStore struct {
+	db *pgxpool.Pool
+}
+
+func NewLoginThrottleStore(db *pgxpool.Pool) *LoginThrottleStore {
+	return &LoginThrottleStore{db: db}
+}
+
+// LockedUntil returns the latest lockout expiry among keys, or the zero time
+// if none of them is currently locked.
+func (s *LoginThrottleStore) LockedUntil(ctx context.Context, keys ...string) (time.Time, error) {
+	var until *time.Time
+	err := s.db.QueryRow(ctx, `
+		SELECT max(locked_until)
+		FROM login_throttles
+		WHERE key = ANY($1) AND locked_until > now()
+	`, keys).Scan(&until)
+	if err != nil || until == nil {
+		return time.Time{}, err
+	}
+	return *until, nil
+}
+
+// RecordFailure increments the failure counter for key and returns the new
+// count. Counters whose last failure is older than windowStart start over.
+func (s *LoginThrottleStore) RecordFailure(ctx context.Context, key string, windowStart time.Time) (int, error) {
+	var failures int
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO login_throttles (key, failures, last_failure_at)
+		VALUES ($1, 1, now())
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN login_throttles.last_failure_at < $2 THEN 1
+				ELSE login_throttles.failures + 1
+			END,
+			last_failure_at = now()
+		RETURNING failures
+	`, key, windowStart).Scan(&failures)
+	return failures, err
+}
+
+func (s *LoginThrottleStore) Lock(ctx context.Context, key string, until time.Time) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE login_throttles
+		SET locked_until = $2
+		WHERE key = $1
+	`, key, until)
+	return err
+}
+
+func (s *LoginThrottleStore) Reset(ctx context.Context, key string) error {
+	_, err := s.db.Exec(ctx, `DELETE FROM login_throttles WHERE key = $1`, key)
+	return err
+}
@@ -0,0 +1,153 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type MFAStore struct {
+	db *pgxpool.Pool
+}
+
+func NewMFAStore(db *pgxpool.Pool) *MFAStore {
+	return &MFAStore{db: db}
+}
+
+type TOTPState struct {
+	Secret    string
+	EnabledAt *time.Time
+	LastStep  *int64
+}
+
+func (s *MFAStore) GetTOTP(ctx context.Context, userID uuid.UUID) (TOTPState, error) {
+	var st TOTPState
+	var secret *string
+	err := s.db.QueryRow(ctx, `
+		SELECT totp_secret, totp_enabled_at, totp_last_step
+		FROM users
+		WHERE id = $1
+	`, userID).Scan(&secret, &st.EnabledAt, &st.LastStep)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return TOTPState{}, pgx.ErrNoRows
+	}
+	if secret != nil {
+		st.Secret = *secret
+	}
+	return st, err
+}
+
+// SetPendingSecret stores a new secret that only takes effect once Enable is
+// called with a valid code. It fails with pgx.ErrNoRows if MFA is already on.
+func (s *MFAStore) SetPendingSecret(ctx context.Context, userID uuid.UUID, secret string) error {
+	tag, err := s.db.Exec(ctx, `
+		UPDATE users
+		SET totp_secret = $2, totp_last_step = NULL
+		WHERE id = $1 AND totp_enabled_at IS NULL
+	`, userID, secret)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// Enable turns on TOTP and replaces the user's recovery codes.
+func (s *MFAStore) Enable(ctx context.Context, userID uuid.UUID, step int64, recoveryCodeHashes []string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE users
+		SET totp_enabled_at = now(), totp_last_step = $2
+		WHERE id = $1 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL
+	`, userID, step)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, h := range recoveryCodeHashes {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO mfa_recovery_codes (user_id, code_hash)
+			VALUES ($1, $2)
+		`, userID, h); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Disable clears the TOTP secret and recovery codes and bumps the token
+// version, so sessions that passed the second factor stop being accepted.
+func (s *MFAStore) Disable(ctx context.Context, userID uuid.UUID) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE users
+		SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL,
+			token_version = token_version + 1
+		WHERE id = $1
+	`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// UseStep records step as the last accepted TOTP step. It reports false if
+// the step (or a later one) was already used, i.e. the code is a replay.
+func (s *MFAStore) UseStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	tag, err := s.db.Exec(ctx, `
+		UPDATE users
+		SET totp_last_step = $2
+		WHERE id = $1 AND (totp_last_step IS NULL OR totp_last_step < $2)
+	`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (s *MFAStore) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	tag, err := s.db.Exec(ctx, `
+		UPDATE mfa_recovery_codes
+		SET used_at = now()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (s *MFAStore) RemainingRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error) {
+	var n int
+	err := s.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL
+	`, userID).Scan(&n)
+	return n, err
+}
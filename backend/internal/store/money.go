@@ -0,0 +1,21 @@
+package store
+
+import (
+	"fmt"
+	"math/big"
+	"mini-product-catalog/internal/money"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// numeric converts m to an exact NUMERIC query argument.
+func numeric(m money.Money) pgtype.Numeric {
+	return pgtype.Numeric{Int: big.NewInt(m.Amount), Exp: int32(-m.Exp()), Valid: true}
+}
+
+func moneyFromNumeric(n pgtype.Numeric, currency string) (money.Money, error) {
+	if !n.Valid || n.NaN || n.InfinityModifier != pgtype.Finite {
+		return money.Money{}, fmt.Errorf("invalid price value")
+	}
+	return money.FromDecimal(n.Int, int(n.Exp), currency)
+}
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"mini-product-catalog/internal/model"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type OIDCStore struct {
+	db *pgxpool.Pool
+}
+
+func NewOIDCStore(db *pgxpool.Pool) *OIDCStore {
+	return &OIDCStore{db: db}
+}
+
+func (s *OIDCStore) CreateState(ctx context.Context, stateHash string, st model.OIDCState, expiresAt time.Time) error {
+	// Opportunistically drop abandoned login attempts.
+	if _, err := s.db.Exec(ctx, `DELETE FROM oidc_states WHERE expires_at < now()`); err != nil {
+		return err
+	}
+
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO oidc_states (state_hash, provider, nonce, code_verifier, redirect_to, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, stateHash, st.Provider, st.Nonce, st.CodeVerifier, st.RedirectTo, expiresAt)
+	return err
+}
+
+// ConsumeState deletes and returns an unexpired state, so each one can be
+// redeemed only once.
+func (s *OIDCStore) ConsumeState(ctx context.Context, stateHash string) (model.OIDCState, error) {
+	var st model.OIDCState
+	err := s.db.QueryRow(ctx, `
+		DELETE FROM oidc_states
+		WHERE state_hash = $1 AND expires_at > now()
+		RETURNING provider, nonce, code_verifier, redirect_to
+	`, stateHash).Scan(&st.Provider, &st.Nonce, &st.CodeVerifier, &st.RedirectTo)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.OIDCState{}, pgx.ErrNoRows
+	}
+	return st, err
+}
+
+const identityColumns = `id, user_id, provider, subject, email, last_login_at, created_at`
+
+func scanIdentity(row pgx.Row) (model.UserIdentity, error) {
+	var i model.UserIdentity
+	err := row.Scan(&i.ID, &i.UserID, &i.Provider, &i.Subject, &i.Email, &i.LastLoginAt, &i.CreatedAt)
+	return i, err
+}
+
+func (s *OIDCStore) GetIdentity(ctx context.Context, provider, subject string) (model.UserIdentity, error) {
+	i, err := scanIdentity(s.db.QueryRow(ctx, `
+		SELECT `+identityColumns+`
+		FROM user_identities
+		WHERE provider = $1 AND subject = $2
+	`, provider, subject))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.UserIdentity{}, pgx.ErrNoRows
+	}
+	return i, err
+}
+
+func (s *OIDCStore) LinkIdentity(ctx context.Context, userID uuid.UUID, provider, subject, email string) (model.UserIdentity, error) {
+	return scanIdentity(s.db.QueryRow(ctx, `
+		INSERT INTO user_identities (user_id, provider, subject, email, last_login_at)
+		VALUES ($1, $2, $3, $4, now())
+		RETURNING `+identityColumns,
+		userID, provider, subject, email))
+}
+
+func (s *OIDCStore) TouchIdentity(ctx context.Context, id uuid.UUID, email string) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE user_identities
+		SET last_login_at = now(), email = $2
+		WHERE id = $1
+	`, id, email)
+	return err
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"mini-product-catalog/internal/model"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ProductCursor marks the last row of a page in keyset pagination. Key is the
+// row's value of the sort column and ID breaks ties between equal keys. Sort
+// and Order pin the cursor to the ordering it was issued for, and Currency to
+// the currency price keys were computed in.
+type ProductCursor struct {
+	Sort     string    `json:"s"`
+	Order    string    `json:"o"`
+	Currency string    `json:"c,omitempty"`
+	Key      string    `json:"k"`
+	ID       uuid.UUID `json:"id"`
+}
+
+func (c ProductCursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeProductCursor(s string) (ProductCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return ProductCursor{}, ErrInvalidCursor
+	}
+
+	var c ProductCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == uuid.Nil {
+		return ProductCursor{}, ErrInvalidCursor
+	}
+	if _, err := c.keyValue(); err != nil {
+		return ProductCursor{}, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// keyValue converts Key back into a value comparable with the sort column.
+func (c ProductCursor) keyValue() (any, error) {
+	switch c.Sort {
+	case "created_at":
+		return time.Parse(time.RFC3339Nano, c.Key)
+	case "price":
+		var n pgtype.Numeric
+		if err := n.Scan(c.Key); err != nil || !n.Valid || n.NaN || n.InfinityModifier != pgtype.Finite {
+			return nil, ErrInvalidCursor
+		}
+		return n, nil
+	}
+	return nil, ErrInvalidCursor
+}
+
+// productCursorFor builds the cursor after p. priceKey is the row's price in
+// currency as computed by the query, since p.Price may be in another one.
+func productCursorFor(p model.Product, sort, order, currency, priceKey string) ProductCursor {
+	c := ProductCursor{Sort: sort, Order: order, ID: p.ID}
+	switch sort {
+	case "created_at":
+		c.Key = p.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case "price":
+		c.Key, c.Currency = priceKey, currency
+	}
+	return c
+}
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"encoding/base64"
+	"mini-product-catalog/internal/model"
+	"mini-product-catalog/internal/money"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestProductCursorRoundTrip(t *testing.T) {
+	p := model.Product{
+		ID:        uuid.MustParse("6f1c2c1e-8a4b-4c1e-9d7e-2b8f3a9c0d11"),
+		CreatedAt: time.Date(2024, 3, 9, 14, 5, 6, 123456789, time.FixedZone("CET", 3600)),
+		Price:     money.Money{Amount: 123456, Currency: "USD"},
+	}
+
+	tests := []struct {
+		sort, order, key string
+	}{
+		{"created_at", "desc", "2024-03-09T13:05:06.123456789Z"},
+		{"created_at", "asc", "2024-03-09T13:05:06.123456789Z"},
+		{"price", "asc", "1234.5600"},
+		{"price", "desc", "1234.5600"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort+"_"+tt.order, func(t *testing.T) {
+			c := productCursorFor(p, tt.sort, tt.order, "EUR", "1234.5600")
+			if c.Key != tt.key {
+				t.Fatalf("key = %q, want %q", c.Key, tt.key)
+			}
+			if want := map[string]string{"price": "EUR"}[tt.sort]; c.Currency != want {
+				t.Fatalf("currency = %q, want %q", c.Currency, want)
+			}
+
+			got, err := DecodeProductCursor(c.Encode())
+			if err != nil {
+				t.Fatalf("DecodeProductCursor: %v", err)
+			}
+			if got != c {
+				t.Fatalf("round trip = %+v, want %+v", got, c)
+			}
+			if _, err := got.keyValue(); err != nil {
+				t.Fatalf("keyValue: %v", err)
+			}
+		})
+	}
+}
+
+func TestDecodeProductCursorRejects(t *testing.T) {
+	id := uuid.MustParse("6f1c2c1e-8a4b-4c1e-9d7e-2b8f3a9c0d11")
+	raw := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	tests := map[string]string{
+		"empty":        "",
+		"not base64":   "!!!",
+		"not json":     raw("nope"),
+		"nil id":       ProductCursor{Sort: "price", Order: "asc", Key: "1"}.Encode(),
+		"unknown sort": ProductCursor{Sort: "name", Order: "asc", Key: "a", ID: id}.Encode(),
+		"bad time":     ProductCursor{Sort: "created_at", Order: "asc", Key: "yesterday", ID: id}.Encode(),
+		"bad price":    ProductCursor{Sort: "price", Order: "asc", Key: "12,50", ID: id}.Encode(),
+		"nan price":    ProductCursor{Sort: "price", Order: "asc", Key: "NaN", ID: id}.Encode(),
+	}
+
+	for name, s := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := DecodeProductCursor(s); err != ErrInvalidCursor {
+				t.Fatalf("err = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
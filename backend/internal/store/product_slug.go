@@ -0,0 +1,110 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const maxSlugLength = 80
+
+// slugify lowercases name and joins its ASCII letters and digits with
+// hyphens: "USB-C Hub (7 in 1)" becomes "usb-c-hub-7-in-1". Names without
+// any become fallback.
+func slugify(name, fallback string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+
+	slug := b.String()
+	if len(slug) > maxSlugLength {
+		slug = strings.TrimRight(slug[:maxSlugLength], "-")
+	}
+	if slug == "" {
+		slug = fallback
+	}
+	return slug
+}
+
+// hasSlugSuffix reports whether slug is base with a "-N" disambiguation
+// suffix, so renames that don't change the base keep their slug.
+func hasSlugSuffix(slug, base string) bool {
+	rest, ok := strings.CutPrefix(slug, base+"-")
+	if !ok || rest == "" {
+		return false
+	}
+	for _, r := range rest {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// availableSlug returns base, or base-2, base-3 and so on, whichever is not
+// taken by another product or another product's old slugs. It holds a
+// transaction-scoped lock on base so concurrent writers don't pick the same
+// one.
+func availableSlug(ctx context.Context, tx pgx.Tx, base string, productID uuid.UUID) (string, error) {
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('product_slug:' || $1))`, base); err != nil {
+		return "", err
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT slug FROM products
+		WHERE (slug = $1 OR slug LIKE $1 || '-%') AND id <> $2
+		UNION
+		SELECT slug FROM product_slug_history
+		WHERE (slug = $1 OR slug LIKE $1 || '-%') AND product_id <> $2
+	`, base, productID)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	used := map[string]bool{}
+	for rows.Next() {
+		var t string
+		if err := rows.Scan(&t); err != nil {
+			return "", err
+		}
+		used[t] = true
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	slug := base
+	for n := 2; used[slug]; n++ {
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+	return slug, nil
+}
+
+// moveSlug records oldSlug in the product's history and drops newSlug from
+// it, in case the product is going back to a name it had before.
+func moveSlug(ctx context.Context, tx pgx.Tx, productID uuid.UUID, oldSlug, newSlug string) error {
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO product_slug_history (slug, product_id)
+		VALUES ($1, $2)
+		ON CONFLICT (slug) DO UPDATE SET product_id = EXCLUDED.product_id, created_at = now()
+	`, oldSlug, productID); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(ctx, `DELETE FROM product_slug_history WHERE slug = $1 AND product_id = $2`, newSlug, productID)
+	return err
+}
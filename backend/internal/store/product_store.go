@@ -2,16 +2,36 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"mini-product-catalog/internal/model"
+	"mini-product-catalog/internal/money"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrVariantCurrency means a variant would be priced in a currency other
+// than its product's.
+var ErrVariantCurrency = errors.New("variants are priced in another currency")
+
+// sortPricesRefreshInterval is how often listings catch product_sort_prices
+// up with exchange rates that have taken effect since they were entered.
+const sortPricesRefreshInterval = time.Minute
+
 type ProductStore struct {
 	db *pgxpool.Pool
+
+	mu                  sync.Mutex
+	sortPricesRefreshed time.Time
 }
 
 func NewProductStore(db *pgxpool.Pool) *ProductStore {
@@ -23,75 +43,334 @@ type ProductListOptions struct {
 	Limit int
 
 	CategoryID *uuid.UUID
-	MinPrice   *float64
-	MaxPrice   *float64
-	Q          string
+	// IncludeSubcategories widens CategoryID to its descendant categories.
+	IncludeSubcategories bool
+	// IncludeHidden keeps products whose category is hidden, itself or
+	// through an ancestor.
+	IncludeHidden bool
+	// MinPrice, MaxPrice, price sorting and price facets compare every
+	// product's price in PriceCurrency (the default currency when empty), so
+	// products stored in different currencies are ranked consistently.
+	// Products with no price in PriceCurrency are left out of them.
+	PriceCurrency string
+	MinPrice      *money.Money
+	MaxPrice      *money.Money
+	Q             string
+	InStock       *bool
+	Attributes    []AttributeFilter
+	// Highlight adds search snippets to each result when Q is set.
+	Highlight bool
 
 	Sort  string
 	Order string
+
+	// Cursor continues after the row it marks instead of using Page.
+	Cursor *ProductCursor
+	// SkipTotal leaves out the COUNT(*) query.
+	SkipTotal bool
 }
 
-func (s *ProductStore) GetByID(ctx context.Context, id uuid.UUID) (model.Product, error) {
+// AttributeFilter matches products on one attribute. Op "eq" matches any of
+// Values; "gt", "gte", "lt" and "lte" compare numeric attributes to Number.
+type AttributeFilter struct {
+	Key    string
+	Op     string
+	Values []string
+	Number float64
+}
+
+var attributeOps = map[string]string{"gt": ">", "gte": ">=", "lt": "<", "lte": "<="}
+
+// attributeCandidates lists the JSON values a query string value may stand
+// for, since "13" could be stored as a number or as text.
+func attributeCandidates(key, v string) []map[string]any {
+	out := []map[string]any{{key: v}}
+	if n, err := strconv.ParseFloat(v, 64); err == nil {
+		out = append(out, map[string]any{key: n})
+	}
+	if v == "true" || v == "false" {
+		out = append(out, map[string]any{key: v == "true"})
+	}
+	return out
+}
+
+// searchQuery turns free text into a prefix-matching tsquery such as
+// "wire:* & mou:*". Only letters and digits survive, so the result is always
+// valid tsquery syntax.
+func searchQuery(q string) string {
+	terms := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, t := range terms {
+		terms[i] = t + ":*"
+	}
+	return strings.Join(terms, " & ")
+}
+
+// productInStock holds when the product itself or any of its variants has
+// unreserved stock.
+const productInStock = `(p.stock_on_hand > p.stock_reserved OR EXISTS (
+	SELECT 1 FROM product_variants v
+	WHERE v.product_id = p.id AND v.stock_on_hand > v.stock_reserved
+))`
+
+const productColumns = `p.id, p.category_id, p.sku, p.slug, p.name, p.description, p.price, p.currency,
+	p.stock_on_hand, p.stock_reserved, ` + productInStock + `, p.created_at, p.updated_at, p.attributes`
+
+// scanProduct reads productColumns followed by the category name and any
+// extra columns.
+func scanProduct(row pgx.Row, extra ...any) (model.Product, error) {
 	var p model.Product
+	var price pgtype.Numeric
+	var currency string
+	dest := append([]any{&p.ID, &p.CategoryID, &p.SKU, &p.Slug, &p.Name, &p.Description, &price, &currency, &p.StockOnHand, &p.StockReserved, &p.InStock, &p.CreatedAt, &p.UpdatedAt, &p.Attributes, &p.CategoryName}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return model.Product{}, err
+	}
+
+	var err error
+	p.Price, err = moneyFromNumeric(price, currency)
+	if err != nil {
+		return model.Product{}, err
+	}
+	return p, nil
+}
+
+func (o ProductListOptions) priceCurrency() string {
+	if o.PriceCurrency == "" {
+		return money.DefaultCurrency()
+	}
+	return o.PriceCurrency
+}
+
+// comparesPrices reports whether a listing filters or sorts by price.
+func (o ProductListOptions) comparesPrices() bool {
+	return o.MinPrice != nil || o.MaxPrice != nil || o.Sort == "price"
+}
+
+// productPrices says where a listing reads the prices it compares.
+type productPrices struct {
+	currency string
+	// convert is set when some product is stored in another currency, so
+	// prices come from product_sort_prices, joined as sp, instead of
+	// products.price.
+	convert bool
+}
+
+func (pp productPrices) column() string {
+	if pp.convert {
+		return "sp.price"
+	}
+	return "p.price"
+}
+
+func (pp productPrices) idColumn() string {
+	if pp.convert {
+		return "sp.product_id"
+	}
+	return "p.id"
+}
+
+// pricesIn works out how to compare prices in currency. While every product
+// is stored in it that is products.price, which idx_products_price_id
+// serves; otherwise it is the materialized product_sort_prices.
+func (s *ProductStore) pricesIn(ctx context.Context, currency string) (productPrices, error) {
+	pp := productPrices{currency: currency}
+	// Two ranges rather than <> so idx_products_currency applies.
 	err := s.db.QueryRow(ctx, `
-		SELECT p.id, p.category_id, c.name, p.name, p.description, p.price::float8, p.created_at, p.updated_at
+		SELECT EXISTS(SELECT 1 FROM products WHERE currency < $1 OR currency > $1)
+	`, currency).Scan(&pp.convert)
+	if err != nil || !pp.convert {
+		return pp, err
+	}
+
+	return pp, s.refreshDueSortPrices(ctx)
+}
+
+// refreshDueSortPrices lets rates whose effective date has passed into
+// product_sort_prices, at most once per sortPricesRefreshInterval. Writes to
+// products, overrides and rates keep it current otherwise.
+func (s *ProductStore) refreshDueSortPrices(ctx context.Context) error {
+	s.mu.Lock()
+	due := time.Since(s.sortPricesRefreshed) >= sortPricesRefreshInterval
+	if due {
+		s.sortPricesRefreshed = time.Now()
+	}
+	s.mu.Unlock()
+	if !due {
+		return nil
+	}
+
+	_, err := s.db.Exec(ctx, `SELECT refresh_due_product_sort_prices()`)
+	return err
+}
+
+const headlineOptions = `StartSel=<mark>, StopSel=</mark>`
+
+const (
+	FacetCategory = "category"
+	FacetPrice    = "price"
+)
+
+func (s *ProductStore) GetByID(ctx context.Context, id uuid.UUID) (model.Product, error) {
+	return scanProduct(s.db.QueryRow(ctx, `
+		SELECT `+productColumns+`, c.name
 		FROM products p
 		JOIN categories c ON c.id = p.category_id
 		WHERE p.id = $1
-	`, id).Scan(&p.ID, &p.CategoryID, &p.CategoryName, &p.Name, &p.Description, &p.Price, &p.CreatedAt, &p.UpdatedAt)
+	`, id))
+}
 
-	if err != nil {
-		return model.Product{}, err
+// GetBySKU finds a product by its SKU, which callers pass upper-cased.
+func (s *ProductStore) GetBySKU(ctx context.Context, sku string) (model.Product, error) {
+	return scanProduct(s.db.QueryRow(ctx, `
+		SELECT `+productColumns+`, c.name
+		FROM products p
+		JOIN categories c ON c.id = p.category_id
+		WHERE p.sku = $1
+	`, sku))
+}
+
+// GetBySlug finds a product by its current slug or, failing that, by a slug
+// it had before being renamed. moved reports the latter case, in which the
+// caller should point clients at p.Slug.
+func (s *ProductStore) GetBySlug(ctx context.Context, slug string) (p model.Product, moved bool, err error) {
+	p, err = scanProduct(s.db.QueryRow(ctx, `
+		SELECT `+productColumns+`, c.name
+		FROM products p
+		JOIN categories c ON c.id = p.category_id
+		WHERE p.slug = $1
+	`, slug))
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return p, false, err
 	}
-	return p, nil
+
+	p, err = scanProduct(s.db.QueryRow(ctx, `
+		SELECT `+productColumns+`, c.name
+		FROM product_slug_history h
+		JOIN products p ON p.id = h.product_id
+		JOIN categories c ON c.id = p.category_id
+		WHERE h.slug = $1
+	`, slug))
+	return p, err == nil, err
 }
 
-func (s *ProductStore) Create(ctx context.Context, categoryID uuid.UUID, name, description string, price float64) (model.Product, error) {
+// Create inserts a product with a slug derived from its name.
+func (s *ProductStore) Create(ctx context.Context, categoryID uuid.UUID, sku, name, description string, price money.Money, attributes map[string]any) (model.Product, error) {
 	var p model.Product
-	err := s.db.QueryRow(ctx, `
-		INSERT INTO products (category_id, name, description, price)
-		VALUES ($1, $2, $3, $4)
-		RETURNING
-			id, category_id,
-			(SELECT name FROM categories WHERE id = $1) AS category_name,
-			name, description, price::float8, created_at, updated_at
-	`, categoryID, name, description, price).Scan(&p.ID, &p.CategoryID, &p.CategoryName, &p.Name, &p.Description, &p.Price, &p.CreatedAt, &p.UpdatedAt)
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if err := claimSKU(ctx, tx, sku, uuid.Nil, uuid.Nil); err != nil {
+			return err
+		}
+		slug, err := availableSlug(ctx, tx, slugify(name, "product"), uuid.Nil)
+		if err != nil {
+			return err
+		}
+
+		p, err = scanProduct(tx.QueryRow(ctx, `
+			INSERT INTO products AS p (category_id, sku, slug, name, description, price, currency, attributes)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING `+productColumns+`,
+				(SELECT name FROM categories WHERE id = $1) AS category_name
+		`, categoryID, sku, slug, name, description, numeric(price), price.Currency, attributes))
+		return err
+	})
 
 	return p, err
 }
 
-func (s *ProductStore) Update(ctx context.Context, id uuid.UUID, categoryID uuid.UUID, name, description string, price float64) (model.Product, error) {
+// Update saves a product. A new name gets a new slug; the old one is kept in
+// the slug history so existing links still resolve.
+func (s *ProductStore) Update(ctx context.Context, id uuid.UUID, categoryID uuid.UUID, sku, name, description string, price money.Money, attributes map[string]any) (model.Product, error) {
 	var p model.Product
-	err := s.db.QueryRow(ctx, `
-		UPDATE products
-		SET category_id = $2,
-			name = $3,
-			description = $4,
-			price = $5,
-			updated_at = now()
-		WHERE id = $1
-		RETURNING
-			id, category_id,
-			(SELECT name FROM categories WHERE id = $2) AS category_name,
-			name, description, price::float8, created_at, updated_at
-	`, id, categoryID, name, description, price).
-		Scan(&p.ID, &p.CategoryID, &p.CategoryName, &p.Name, &p.Description, &p.Price, &p.CreatedAt, &p.UpdatedAt)
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		var oldSlug string
+		if err := tx.QueryRow(ctx, `SELECT slug FROM products WHERE id = $1 FOR UPDATE`, id).Scan(&oldSlug); err != nil {
+			return err
+		}
+		if err := claimSKU(ctx, tx, sku, id, uuid.Nil); err != nil {
+			return err
+		}
+
+		var mixed bool
+		err := tx.QueryRow(ctx, `
+			SELECT EXISTS(SELECT 1 FROM product_variants WHERE product_id = $1 AND currency <> $2)
+		`, id, price.Currency).Scan(&mixed)
+		if err != nil {
+			return err
+		}
+		if mixed {
+			return ErrVariantCurrency
+		}
+
+		slug := oldSlug
+		if base := slugify(name, "product"); base != oldSlug && !hasSlugSuffix(oldSlug, base) {
+			var err error
+			slug, err = availableSlug(ctx, tx, base, id)
+			if err != nil {
+				return err
+			}
+			if err := moveSlug(ctx, tx, id, oldSlug, slug); err != nil {
+				return err
+			}
+		}
+
+		p, err = scanProduct(tx.QueryRow(ctx, `
+			UPDATE products p
+			SET category_id = $2,
+				sku = $3,
+				slug = $4,
+				name = $5,
+				description = $6,
+				price = $7,
+				currency = $8,
+				attributes = $9,
+				updated_at = now()
+			WHERE id = $1
+			RETURNING `+productColumns+`,
+				(SELECT name FROM categories WHERE id = $2) AS category_name
+		`, id, categoryID, sku, slug, name, description, numeric(price), price.Currency, attributes))
+		return err
+	})
 
 	return p, err
 }
 
+// Delete removes a product unless stock is reserved for it or one of its
+// variants, which is ErrStockReserved. Its stock movements stay in the
+// ledger.
 func (s *ProductStore) Delete(ctx context.Context, id uuid.UUID) (model.Product, error) {
-	var p model.Product
-	err := s.db.QueryRow(ctx, `
-		DELETE FROM products
-		WHERE id = $1
-		RETURNING id, category_id, ''::text as category_name, name, description, price::float8, created_at, updated_at
-	`, id).Scan(&p.ID, &p.CategoryID, &p.CategoryName, &p.Name, &p.Description, &p.Price, &p.CreatedAt, &p.UpdatedAt)
+	p, err := scanProduct(s.db.QueryRow(ctx, `
+		DELETE FROM products p
+		WHERE id = $1 AND stock_reserved = 0
+			AND NOT EXISTS(SELECT 1 FROM product_variants v WHERE v.product_id = p.id AND v.stock_reserved > 0)
+		RETURNING `+productColumns+`, ''::text AS category_name
+	`, id))
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return p, err
+	}
 
-	return p, err
+	var exists bool
+	if err := s.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM products WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return model.Product{}, err
+	}
+	if exists {
+		return model.Product{}, ErrStockReserved
+	}
+	return model.Product{}, pgx.ErrNoRows
+}
+
+// ProductPage is one page of a product listing. Total is nil when the count
+// was skipped, and NextCursor is empty on the last page.
+type ProductPage struct {
+	Items      []model.Product
+	Total      *int
+	NextCursor string
 }
 
-func (s *ProductStore) List(ctx context.Context, opt ProductListOptions) ([]model.Product, int, error) {
+// List pages through products either by offset (Page) or, when Cursor is
+// set, by keyset on the sort column and id. A cursor makes Page irrelevant.
+func (s *ProductStore) List(ctx context.Context, opt ProductListOptions) (ProductPage, error) {
 	if opt.Page < 1 {
 		opt.Page = 1
 	}
@@ -103,79 +382,321 @@ func (s *ProductStore) List(ctx context.Context, opt ProductListOptions) ([]mode
 	}
 	offset := (opt.Page - 1) * opt.Limit
 
-	sortCol := "p.created_at"
-	if opt.Sort == "price" {
-		sortCol = "p.price"
-	}
-	order := "DESC"
-	if strings.ToLower(opt.Order) == "asc" {
-		order = "ASC"
+	var prices productPrices
+	if opt.comparesPrices() {
+		var err error
+		if prices, err = s.pricesIn(ctx, opt.priceCurrency()); err != nil {
+			return ProductPage{}, err
+		}
 	}
 
-	conds := []string{"1=1"}
-	args := []any{}
-	argN := 1
+	from, conds, args, query := productFilters(opt, prices, "")
+	whereSQL := strings.Join(conds, " AND ")
 
-	if opt.CategoryID != nil {
-		conds = append(conds, fmt.Sprintf("p.category_id = $%d", argN))
-		args = append(args, *opt.CategoryID)
-		argN++
-	}
-	if opt.MinPrice != nil {
-		conds = append(conds, fmt.Sprintf("p.price >= $%d", argN))
-		args = append(args, *opt.MinPrice)
-		argN++
+	page := ProductPage{}
+
+	if !opt.SkipTotal {
+		var total int
+		if err := s.db.QueryRow(ctx, `
+			SELECT COUNT(*)
+			FROM `+from+`
+			WHERE `+whereSQL, args...,
+		).Scan(&total); err != nil {
+			return ProductPage{}, err
+		}
+		page.Total = &total
 	}
-	if opt.MaxPrice != nil {
-		conds = append(conds, fmt.Sprintf("p.price <= $%d", argN))
-		args = append(args, *opt.MaxPrice)
-		argN++
+
+	sortKey, sortCol, sortType, idCol := "created_at", "p.created_at", "timestamptz", "p.id"
+	keySQL := "NULL::text"
+	if opt.Sort == "price" {
+		sortKey, sortCol, sortType, idCol = "price", prices.column(), "numeric", prices.idColumn()
+		keySQL = sortCol + "::text"
 	}
-	if strings.TrimSpace(opt.Q) != "" {
-		conds = append(conds, fmt.Sprintf("p.name ILIKE $%d", argN))
-		args = append(args, "%"+strings.TrimSpace(opt.Q)+"%")
-		argN++
+	order, cmp := "DESC", "<"
+	if strings.ToLower(opt.Order) == "asc" {
+		order, cmp = "ASC", ">"
 	}
+	orderSQL := sortCol + " " + order + ", " + idCol + " " + order
 
-	whereSQL := strings.Join(conds, " AND ")
-
-	var total int
+	highlightSQL := "NULL::text, NULL::text"
+	relevance := false
+	if query != "" {
+		if opt.Sort == "relevance" {
+			relevance = true
+			orderSQL = "ts_rank_cd(p.search_vector, " + query + ") DESC, p.created_at DESC, p.id DESC"
+		}
+		if opt.Highlight {
+			highlightSQL = fmt.Sprintf(`
+				ts_headline('simple', p.name, %[1]s, '%[2]s, HighlightAll=true'),
+				ts_headline('simple', p.description, %[1]s, '%[2]s, MaxFragments=2, MaxWords=20, MinWords=5')`,
+				query, headlineOptions)
+		}
+	}
 
-	if err := s.db.QueryRow(ctx, `
-		SELECT COUNT(*)
-		FROM products p
-		WHERE `+whereSQL, args...,
-	).Scan(&total); err != nil {
-		return nil, 0, err
+	if opt.Cursor != nil {
+		// Relevance scores aren't stable enough to resume from, and price
+		// keys only compare within the currency they were computed in.
+		if relevance || opt.Cursor.Sort != sortKey || opt.Cursor.Order != strings.ToLower(order) ||
+			(sortKey == "price" && opt.Cursor.Currency != opt.priceCurrency()) {
+			return ProductPage{}, ErrInvalidCursor
+		}
+		key, err := opt.Cursor.keyValue()
+		if err != nil {
+			return ProductPage{}, ErrInvalidCursor
+		}
+		args = append(args, key, opt.Cursor.ID)
+		whereSQL += fmt.Sprintf(" AND (%s, %s) %s ($%d::%s, $%d)", sortCol, idCol, cmp, len(args)-1, sortType, len(args))
+		offset = 0
 	}
 
-	argsList := append(args, opt.Limit, offset)
-	limitPos := argN
-	offsetPos := argN + 1
+	// One extra row tells whether another page follows.
+	argsList := append(args, opt.Limit+1, offset)
+	limitPos := len(args) + 1
+	offsetPos := len(args) + 2
 
 	rows, err := s.db.Query(ctx, `
-		SELECT p.id, p.category_id, c.name, p.name, p.description, p.price::float8, p.created_at, p.updated_at
-		FROM products p
+		SELECT `+productColumns+`, c.name,
+			`+highlightSQL+`,
+			vr.min_price, vr.max_price, `+keySQL+`
+		FROM `+from+`
 		JOIN categories c ON c.id = p.category_id
+		LEFT JOIN LATERAL (
+			SELECT MIN(v.price) AS min_price, MAX(v.price) AS max_price
+			FROM product_variants v
+			WHERE v.product_id = p.id
+		) vr ON true
 		WHERE `+whereSQL+`
-		ORDER BY `+sortCol+` `+order+`
+		ORDER BY `+orderSQL+`
 		LIMIT $`+fmt.Sprint(limitPos)+` OFFSET $`+fmt.Sprint(offsetPos), argsList...)
 	if err != nil {
-		return nil, 0, err
+		return ProductPage{}, err
 	}
 	defer rows.Close()
 
 	out := []model.Product{}
+	var priceKeys []string
 	for rows.Next() {
-		var p model.Product
-		if err := rows.Scan(&p.ID, &p.CategoryID, &p.CategoryName, &p.Name, &p.Description, &p.Price, &p.CreatedAt, &p.UpdatedAt); err != nil {
-			return nil, 0, err
+		var hlName, hlDescription *string
+		var minPrice, maxPrice pgtype.Numeric
+		var priceKey *string
+		p, err := scanProduct(rows, &hlName, &hlDescription, &minPrice, &maxPrice, &priceKey)
+		if err != nil {
+			return ProductPage{}, err
+		}
+		if hlName != nil && hlDescription != nil {
+			p.Highlights = &model.ProductHighlights{Name: *hlName, Description: *hlDescription}
+		}
+		// Variant prices share the product's currency.
+		if minPrice.Valid && maxPrice.Valid {
+			var pr model.PriceRange
+			if pr.Min, err = moneyFromNumeric(minPrice, p.Price.Currency); err != nil {
+				return ProductPage{}, err
+			}
+			if pr.Max, err = moneyFromNumeric(maxPrice, p.Price.Currency); err != nil {
+				return ProductPage{}, err
+			}
+			p.PriceRange = &pr
 		}
 		out = append(out, p)
+		if priceKey != nil {
+			priceKeys = append(priceKeys, *priceKey)
+		}
 	}
 	if err := rows.Err(); err != nil {
-		return nil, 0, err
+		return ProductPage{}, err
+	}
+
+	if len(out) > opt.Limit {
+		out = out[:opt.Limit]
+		if !relevance {
+			var priceKey string
+			if sortKey == "price" {
+				priceKey = priceKeys[opt.Limit-1]
+			}
+			page.NextCursor = productCursorFor(out[len(out)-1], sortKey, strings.ToLower(order), opt.priceCurrency(), priceKey).Encode()
+		}
+	}
+	page.Items = out
+
+	return page, nil
+}
+
+// productFilters builds the FROM clause and WHERE conditions for opt,
+// numbering placeholders from $1. The filter belonging to the facet named by
+// skip is left out, so that facet's counts show what picking another value
+// would return. query is the tsquery expression when a search term is
+// present. prices.column() is usable whenever prices were looked up for a
+// price filter, price sort or the price facet.
+func productFilters(opt ProductListOptions, prices productPrices, skip string) (from string, conds []string, args []any, query string) {
+	from = "products p"
+	conds = []string{"1=1"}
+	argN := 1
+
+	if prices.convert {
+		from += fmt.Sprintf(" JOIN product_sort_prices sp ON sp.product_id = p.id AND sp.currency = $%d", argN)
+		args = append(args, prices.currency)
+		argN++
+	}
+
+	if !opt.IncludeHidden {
+		conds = append(conds, "p.category_id IN ("+visibleCategoryIDs+")")
+	}
+	if opt.CategoryID != nil && skip != FacetCategory {
+		if opt.IncludeSubcategories {
+			conds = append(conds, fmt.Sprintf(`p.category_id IN (
+				WITH RECURSIVE subtree AS (
+					SELECT id FROM categories WHERE id = $%d
+					UNION
+					SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+				)
+				SELECT id FROM subtree
+			)`, argN))
+		} else {
+			conds = append(conds, fmt.Sprintf("p.category_id = $%d", argN))
+		}
+		args = append(args, *opt.CategoryID)
+		argN++
+	}
+	if skip != FacetPrice {
+		price := prices.column()
+		if opt.MinPrice != nil {
+			conds = append(conds, fmt.Sprintf("%s >= $%d", price, argN))
+			args = append(args, numeric(*opt.MinPrice))
+			argN++
+		}
+		if opt.MaxPrice != nil {
+			conds = append(conds, fmt.Sprintf("%s <= $%d", price, argN))
+			args = append(args, numeric(*opt.MaxPrice))
+			argN++
+		}
+	}
+	if opt.InStock != nil {
+		if *opt.InStock {
+			conds = append(conds, productInStock)
+		} else {
+			conds = append(conds, "NOT "+productInStock)
+		}
+	}
+	// Equality uses @> so the GIN index on attributes applies. Ranges use a
+	// jsonpath filter, which is false rather than an error for non-numbers.
+	for _, f := range opt.Attributes {
+		if sqlOp, ok := attributeOps[f.Op]; ok {
+			conds = append(conds, fmt.Sprintf("p.attributes @? $%d::jsonpath", argN))
+			args = append(args, fmt.Sprintf(`$.%q ? (@ %s %s)`, f.Key, sqlOp, strconv.FormatFloat(f.Number, 'f', -1, 64)))
+			argN++
+			continue
+		}
+
+		var ors []string
+		for _, v := range f.Values {
+			for _, c := range attributeCandidates(f.Key, v) {
+				ors = append(ors, fmt.Sprintf("p.attributes @> $%d::jsonb", argN))
+				args = append(args, c)
+				argN++
+			}
+		}
+		conds = append(conds, "("+strings.Join(ors, " OR ")+")")
+	}
+	if tsq := searchQuery(opt.Q); tsq != "" {
+		query = fmt.Sprintf("to_tsquery('simple', $%d)", argN)
+		conds = append(conds, "p.search_vector @@ "+query)
+		args = append(args, tsq)
+	}
+
+	return from, conds, args, query
+}
+
+// Facets counts the products matching opt per category and per price bucket.
+// Each facet ignores its own filter. priceBuckets are ascending boundaries in
+// opt.PriceCurrency: 100,500 yields the buckets [0,100), [100,500) and
+// [500,∞). Products with no price in that currency fall in no bucket.
+func (s *ProductStore) Facets(ctx context.Context, opt ProductListOptions, facets []string, priceBuckets []money.Money) (model.ProductFacets, error) {
+	out := model.ProductFacets{}
+
+	wantPrice := slices.Contains(facets, FacetPrice) && len(priceBuckets) > 0
+	var prices productPrices
+	if opt.comparesPrices() || wantPrice {
+		var err error
+		if prices, err = s.pricesIn(ctx, opt.priceCurrency()); err != nil {
+			return model.ProductFacets{}, err
+		}
+	}
+
+	if slices.Contains(facets, FacetCategory) {
+		// Only join prices when the listing itself does, so the counts cover
+		// the same products.
+		catPrices := prices
+		if !opt.comparesPrices() {
+			catPrices = productPrices{}
+		}
+		from, conds, args, _ := productFilters(opt, catPrices, FacetCategory)
+		rows, err := s.db.Query(ctx, `
+			SELECT c.id, c.name, COUNT(*)
+			FROM `+from+`
+			JOIN categories c ON c.id = p.category_id
+			WHERE `+strings.Join(conds, " AND ")+`
+			GROUP BY c.id, c.name
+			ORDER BY COUNT(*) DESC, c.name ASC`, args...)
+		if err != nil {
+			return model.ProductFacets{}, err
+		}
+		defer rows.Close()
+
+		out.Category = []model.CategoryFacet{}
+		for rows.Next() {
+			var f model.CategoryFacet
+			if err := rows.Scan(&f.ID, &f.Name, &f.Count); err != nil {
+				return model.ProductFacets{}, err
+			}
+			out.Category = append(out.Category, f)
+		}
+		if err := rows.Err(); err != nil {
+			return model.ProductFacets{}, err
+		}
+	}
+
+	if wantPrice {
+		from, conds, args, _ := productFilters(opt, prices, FacetPrice)
+		bounds := make([]pgtype.Numeric, len(priceBuckets))
+		for i, b := range priceBuckets {
+			bounds[i] = numeric(b)
+		}
+		args = append(args, bounds)
+		rows, err := s.db.Query(ctx, `
+			SELECT width_bucket(`+prices.column()+`, $`+fmt.Sprint(len(args))+`::numeric[]) AS bucket, COUNT(*)
+			FROM `+from+`
+			WHERE `+strings.Join(conds, " AND ")+`
+			GROUP BY bucket`, args...)
+		if err != nil {
+			return model.ProductFacets{}, err
+		}
+		defer rows.Close()
+
+		counts := make([]int, len(priceBuckets)+1)
+		for rows.Next() {
+			var bucket, n int
+			if err := rows.Scan(&bucket, &n); err != nil {
+				return model.ProductFacets{}, err
+			}
+			counts[bucket] = n
+		}
+		if err := rows.Err(); err != nil {
+			return model.ProductFacets{}, err
+		}
+
+		out.Price = make([]model.PriceBucket, len(counts))
+		for i, n := range counts {
+			b := model.PriceBucket{Min: money.Money{Currency: priceBuckets[0].Currency}, Count: n}
+			if i > 0 {
+				b.Min = priceBuckets[i-1]
+			}
+			if i < len(priceBuckets) {
+				b.Max = &priceBuckets[i]
+			}
+			out.Price[i] = b
+		}
 	}
 
-	return out, total, nil
+	return out, nil
 }
@@ -0,0 +1,194 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"mini-product-catalog/internal/model"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("refresh token invalid")
+	ErrRefreshTokenExpired = errors.New("refresh token expired")
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
+)
+
+type sessionEntry struct {
+	revoked  bool
+	loadedAt time.Time
+}
+
+type RefreshTokenStore struct {
+	db *pgxpool.Pool
+
+	mu       sync.Mutex
+	sessions map[uuid.UUID]sessionEntry
+}
+
+func NewRefreshTokenStore(db *pgxpool.Pool) *RefreshTokenStore {
+	return &RefreshTokenStore{db: db, sessions: map[uuid.UUID]sessionEntry{}}
+}
+
+const refreshTokenColumns = `id, user_id, family_id, mfa, expires_at, used_at, revoked_at, created_at`
+
+func scanRefreshToken(row pgx.Row) (model.RefreshToken, error) {
+	var t model.RefreshToken
+	err := row.Scan(&t.ID, &t.UserID, &t.FamilyID, &t.MFA, &t.ExpiresAt, &t.UsedAt, &t.RevokedAt, &t.CreatedAt)
+	return t, err
+}
+
+func (s *RefreshTokenStore) Create(ctx context.Context, userID, familyID uuid.UUID, mfa bool, tokenHash string, expiresAt time.Time) (model.RefreshToken, error) {
+	return scanRefreshToken(s.db.QueryRow(ctx, `
+		INSERT INTO refresh_tokens (user_id, family_id, mfa, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+refreshTokenColumns,
+		userID, familyID, mfa, tokenHash, expiresAt))
+}
+
+// Rotate marks the token identified by oldHash as used and issues its
+// successor in the same family. Presenting a token that was already used or
+// revoked revokes the whole family and returns ErrRefreshTokenReused.
+func (s *RefreshTokenStore) Rotate(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (model.RefreshToken, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return model.RefreshToken{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	old, err := scanRefreshToken(tx.QueryRow(ctx, `
+		SELECT `+refreshTokenColumns+`
+		FROM refresh_tokens
+		WHERE token_hash = $1
+		FOR UPDATE
+	`, oldHash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.RefreshToken{}, ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return model.RefreshToken{}, err
+	}
+
+	if old.UsedAt != nil || old.RevokedAt != nil {
+		if _, err := tx.Exec(ctx, `
+			UPDATE refresh_tokens
+			SET revoked_at = now()
+			WHERE family_id = $1 AND revoked_at IS NULL
+		`, old.FamilyID); err != nil {
+			return model.RefreshToken{}, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return model.RefreshToken{}, err
+		}
+		s.forgetSession(old.FamilyID)
+		return old, ErrRefreshTokenReused
+	}
+
+	if time.Now().After(old.ExpiresAt) {
+		return model.RefreshToken{}, ErrRefreshTokenExpired
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE refresh_tokens SET used_at = now() WHERE id = $1`, old.ID); err != nil {
+		return model.RefreshToken{}, err
+	}
+
+	next, err := scanRefreshToken(tx.QueryRow(ctx, `
+		INSERT INTO refresh_tokens (user_id, family_id, mfa, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+refreshTokenColumns,
+		old.UserID, old.FamilyID, old.MFA, newHash, expiresAt))
+	if err != nil {
+		return model.RefreshToken{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return model.RefreshToken{}, err
+	}
+
+	return next, nil
+}
+
+// RevokeByHash revokes the whole session (family) the token belongs to.
+func (s *RefreshTokenStore) RevokeByHash(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	var familyID uuid.UUID
+	err := s.db.QueryRow(ctx, `
+		WITH t AS (
+			SELECT family_id FROM refresh_tokens WHERE token_hash = $1
+		)
+		UPDATE refresh_tokens r
+		SET revoked_at = now()
+		FROM t
+		WHERE r.family_id = t.family_id AND r.revoked_at IS NULL
+		RETURNING t.family_id
+	`, tokenHash).Scan(&familyID)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, pgx.ErrNoRows
+	}
+	if err == nil {
+		s.forgetSession(familyID)
+	}
+	return familyID, err
+}
+
+func (s *RefreshTokenStore) RevokeFamily(ctx context.Context, familyID uuid.UUID) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE refresh_tokens
+		SET revoked_at = now()
+		WHERE family_id = $1 AND revoked_at IS NULL
+	`, familyID)
+	s.forgetSession(familyID)
+	return err
+}
+
+// SessionRevoked reports whether the session (family) was logged out or
+// revoked, so access tokens carrying it as sid stop working before they
+// expire. Revocation always covers the whole family, so one revoked token is
+// enough. Results are cached for userStatusTTL like UserStore.Status.
+func (s *RefreshTokenStore) SessionRevoked(ctx context.Context, familyID uuid.UUID) (bool, error) {
+	s.mu.Lock()
+	e, ok := s.sessions[familyID]
+	s.mu.Unlock()
+	if ok && time.Since(e.loadedAt) < userStatusTTL {
+		return e.revoked, nil
+	}
+
+	var revoked bool
+	err := s.db.QueryRow(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM refresh_tokens
+			WHERE family_id = $1 AND revoked_at IS NOT NULL
+		)
+	`, familyID).Scan(&revoked)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	if len(s.sessions) > 10_000 {
+		s.sessions = map[uuid.UUID]sessionEntry{}
+	}
+	s.sessions[familyID] = sessionEntry{revoked: revoked, loadedAt: time.Now()}
+	s.mu.Unlock()
+
+	return revoked, nil
+}
+
+func (s *RefreshTokenStore) forgetSession(familyID uuid.UUID) {
+	s.mu.Lock()
+	delete(s.sessions, familyID)
+	s.mu.Unlock()
+}
+
+func (s *RefreshTokenStore) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE refresh_tokens
+		SET revoked_at = now()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID)
+	return err
+}
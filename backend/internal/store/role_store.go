@@ -0,0 +1,202 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"mini-product-catalog/internal/model"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrRoleNotFound = errors.New("role not found")
+
+// permissionCacheTTL bounds how long another replica may keep serving a
+// role's old permission set after it was changed.
+const permissionCacheTTL = 30 * time.Second
+
+type RoleStore struct {
+	db *pgxpool.Pool
+
+	mu       sync.Mutex
+	cache    map[string][]string
+	cachedAt time.Time
+}
+
+func NewRoleStore(db *pgxpool.Pool) *RoleStore {
+	return &RoleStore{db: db}
+}
+
+func (s *RoleStore) List(ctx context.Context) ([]model.Role, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT r.name, r.description, r.created_at,
+			COALESCE(array_agg(rp.permission ORDER BY rp.permission) FILTER (WHERE rp.permission IS NOT NULL), '{}')
+		FROM roles r
+		LEFT JOIN role_permissions rp ON rp.role_name = r.name
+		GROUP BY r.name
+		ORDER BY r.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []model.Role{}
+	for rows.Next() {
+		var role model.Role
+		if err := rows.Scan(&role.Name, &role.Description, &role.CreatedAt, &role.Permissions); err != nil {
+			return nil, err
+		}
+		out = append(out, role)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (s *RoleStore) Get(ctx context.Context, name string) (model.Role, error) {
+	var role model.Role
+	err := s.db.QueryRow(ctx, `
+		SELECT r.name, r.description, r.created_at,
+			COALESCE(array_agg(rp.permission ORDER BY rp.permission) FILTER (WHERE rp.permission IS NOT NULL), '{}')
+		FROM roles r
+		LEFT JOIN role_permissions rp ON rp.role_name = r.name
+		WHERE r.name = $1
+		GROUP BY r.name
+	`, name).Scan(&role.Name, &role.Description, &role.CreatedAt, &role.Permissions)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.Role{}, pgx.ErrNoRows
+	}
+	return role, err
+}
+
+func (s *RoleStore) ListPermissions(ctx context.Context) ([]model.Permission, error) {
+	rows, err := s.db.Query(ctx, `SELECT name, description FROM permissions ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []model.Permission{}
+	for rows.Next() {
+		var p model.Permission
+		if err := rows.Scan(&p.Name, &p.Description); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (s *RoleStore) Create(ctx context.Context, name, description string, permissions []string) (model.Role, error) {
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `INSERT INTO roles (name, description) VALUES ($1, $2)`, name, description); err != nil {
+			return err
+		}
+		return setRolePermissions(ctx, tx, name, permissions)
+	})
+	if err != nil {
+		return model.Role{}, err
+	}
+
+	s.invalidate()
+	return s.Get(ctx, name)
+}
+
+func (s *RoleStore) Update(ctx context.Context, name, description string, permissions []string) (model.Role, error) {
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `UPDATE roles SET description = $2 WHERE name = $1`, name, description)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		return setRolePermissions(ctx, tx, name, permissions)
+	})
+	if err != nil {
+		return model.Role{}, err
+	}
+
+	s.invalidate()
+	return s.Get(ctx, name)
+}
+
+func setRolePermissions(ctx context.Context, tx pgx.Tx, role string, permissions []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM role_permissions WHERE role_name = $1`, role); err != nil {
+		return err
+	}
+	if len(permissions) == 0 {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `
+		INSERT INTO role_permissions (role_name, permission)
+		SELECT $1, unnest($2::text[])
+		ON CONFLICT DO NOTHING
+	`, role, permissions)
+	return err
+}
+
+func (s *RoleStore) Delete(ctx context.Context, name string) (bool, error) {
+	tag, err := s.db.Exec(ctx, `DELETE FROM roles WHERE name = $1`, name)
+	if err != nil {
+		return false, err
+	}
+
+	s.invalidate()
+	return tag.RowsAffected() > 0, nil
+}
+
+// IsStaff reports whether role grants any permission. Such roles are held to
+// the staff MFA requirement; customer roles grant none.
+func (s *RoleStore) IsStaff(ctx context.Context, role string) (bool, error) {
+	perms, err := s.Permissions(ctx, role)
+	return len(perms) > 0, err
+}
+
+// Permissions returns the permissions granted to role, served from an
+// in-process cache that is reloaded in full every permissionCacheTTL.
+func (s *RoleStore) Permissions(ctx context.Context, role string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cache == nil || time.Since(s.cachedAt) > permissionCacheTTL {
+		rows, err := s.db.Query(ctx, `SELECT role_name, permission FROM role_permissions`)
+		if err != nil {
+			return nil, err
+		}
+		cache := map[string][]string{}
+		for rows.Next() {
+			var r, p string
+			if err := rows.Scan(&r, &p); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			cache[r] = append(cache[r], p)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		s.cache = cache
+		s.cachedAt = time.Now()
+	}
+
+	return s.cache[role], nil
+}
+
+func (s *RoleStore) invalidate() {
+	s.mu.Lock()
+	s.cache = nil
+	s.mu.Unlock()
+}
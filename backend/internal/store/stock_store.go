@@ -0,0 +1,149 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"mini-product-catalog/internal/model"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// ErrStockReserved means a product or variant can't be deleted while stock
+// is reserved for it.
+var ErrStockReserved = errors.New("stock is reserved")
+
+type StockStore struct {
+	db *pgxpool.Pool
+}
+
+func NewStockStore(db *pgxpool.Pool) *StockStore {
+	return &StockStore{db: db}
+}
+
+// StockChange describes one ledger entry to apply to a product, or to one of
+// its variants when VariantID is set.
+type StockChange struct {
+	VariantID     *uuid.UUID
+	Kind          string
+	OnHandDelta   int
+	ReservedDelta int
+	Reason        string
+	Reference     string
+	ActorKind     string
+	ActorID       uuid.UUID
+}
+
+const stockMovementColumns = `id, product_id, product_sku, variant_id, variant_sku, kind, on_hand_delta, reserved_delta, on_hand_after, reserved_after, reason, reference, actor_kind, actor_id, created_at`
+
+func scanStockMovement(row pgx.Row) (model.StockMovement, error) {
+	var m model.StockMovement
+	err := row.Scan(&m.ID, &m.ProductID, &m.ProductSKU, &m.VariantID, &m.VariantSKU, &m.Kind, &m.OnHandDelta, &m.ReservedDelta, &m.OnHandAfter, &m.ReservedAfter, &m.Reason, &m.Reference, &m.ActorKind, &m.ActorID, &m.CreatedAt)
+	return m, err
+}
+
+// Apply changes a product's or variant's quantities and records the movement
+// in one transaction. The guarded UPDATE row-locks the row, so concurrent
+// decrements can't take stock below zero or below what is reserved.
+func (s *StockStore) Apply(ctx context.Context, productID uuid.UUID, c StockChange) (model.StockMovement, error) {
+	// $1 and $2 pick the row: a product matches on its id twice, a variant
+	// on its own id and its product's.
+	table, match, id := "products", "id = $1 AND id = $2", productID
+	if c.VariantID != nil {
+		table, match, id = "product_variants", "id = $1 AND product_id = $2", *c.VariantID
+	}
+
+	var m model.StockMovement
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		var onHand, reserved int
+		err := tx.QueryRow(ctx, `
+			UPDATE `+table+`
+			SET stock_on_hand = stock_on_hand + $3,
+				stock_reserved = stock_reserved + $4,
+				updated_at = now()
+			WHERE `+match+`
+				AND stock_reserved + $4 >= 0
+				AND stock_on_hand + $3 >= stock_reserved + $4
+			RETURNING stock_on_hand, stock_reserved
+		`, id, productID, c.OnHandDelta, c.ReservedDelta).Scan(&onHand, &reserved)
+		if errors.Is(err, pgx.ErrNoRows) {
+			var exists bool
+			if err := tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM `+table+` WHERE `+match+`)`, id, productID).Scan(&exists); err != nil {
+				return err
+			}
+			if exists {
+				return ErrInsufficientStock
+			}
+			return pgx.ErrNoRows
+		}
+		if err != nil {
+			return err
+		}
+
+		var reference *string
+		if c.Reference != "" {
+			reference = &c.Reference
+		}
+
+		m, err = scanStockMovement(tx.QueryRow(ctx, `
+			INSERT INTO stock_movements
+				(product_id, product_sku, variant_id, variant_sku, kind, on_hand_delta, reserved_delta, on_hand_after, reserved_after, reason, reference, actor_kind, actor_id)
+			VALUES ($1, (SELECT sku FROM products WHERE id = $1), $2, (SELECT sku FROM product_variants WHERE id = $2), $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			RETURNING `+stockMovementColumns,
+			productID, c.VariantID, c.Kind, c.OnHandDelta, c.ReservedDelta, onHand, reserved, c.Reason, reference, c.ActorKind, c.ActorID))
+		return err
+	})
+
+	return m, err
+}
+
+// ListMovements lists a product's movements, including its variants', or only
+// one variant's when variantID is set.
+func (s *StockStore) ListMovements(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID, page, limit int) ([]model.StockMovement, int, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	var total int
+	if err := s.db.QueryRow(ctx, `
+		SELECT COUNT(*) FROM stock_movements
+		WHERE product_id = $1 AND ($2::uuid IS NULL OR variant_id = $2)
+	`, productID, variantID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT `+stockMovementColumns+`
+		FROM stock_movements
+		WHERE product_id = $1 AND ($2::uuid IS NULL OR variant_id = $2)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3 OFFSET $4
+	`, productID, variantID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	out := []model.StockMovement{}
+	for rows.Next() {
+		m, err := scanStockMovement(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return out, total, nil
+}
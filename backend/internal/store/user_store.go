@@ -3,39 +3,80 @@ package store
 import (
 	"context"
 	"errors"
+	"fmt"
 	"mini-product-catalog/internal/model"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// userStatusTTL bounds how long a revoked access token may keep working on
+// a replica other than the one that made the change.
+const userStatusTTL = 5 * time.Second
+
+type UserStatus struct {
+	Exists       bool
+	Disabled     bool
+	TokenVersion int
+}
+
+type userStatusEntry struct {
+	status   UserStatus
+	loadedAt time.Time
+}
+
 type UserStore struct {
 	db *pgxpool.Pool
+
+	mu       sync.Mutex
+	statuses map[uuid.UUID]userStatusEntry
 }
 
 func NewUserStore(db *pgxpool.Pool) *UserStore {
-	return &UserStore{db: db}
+	return &UserStore{db: db, statuses: map[uuid.UUID]userStatusEntry{}}
 }
 
-func (s *UserStore) Create(ctx context.Context, name, email, passwordHash, role string) (model.User, error) {
+type UserListOptions struct {
+	Page  int
+	Limit int
+
+	Q        string
+	Role     string
+	Disabled *bool
+}
+
+type UserUpdate struct {
+	Name     *string
+	Role     *string
+	Disabled *bool
+}
+
+const userColumns = `id, name, email, password_hash, token_version, role, email_verified_at, totp_enabled_at, disabled_at, created_at`
+
+func scanUser(row pgx.Row) (model.User, error) {
 	var u model.User
-	err := s.db.QueryRow(ctx, `
+	err := row.Scan(&u.ID, &u.Name, &u.Email, &u.PasswordHash, &u.TokenVersion, &u.Role, &u.EmailVerifiedAt, &u.MFAEnabledAt, &u.DisabledAt, &u.CreatedAt)
+	return u, err
+}
+
+func (s *UserStore) Create(ctx context.Context, name, email, passwordHash, role string) (model.User, error) {
+	return scanUser(s.db.QueryRow(ctx, `
 		INSERT INTO users (name, email, password_hash, role)
 		VALUES ($1, $2, $3, $4)
-		RETURNING id, name, email, password_hash, role, created_at
-	`, name, email, passwordHash, role).
-		Scan(&u.ID, &u.Name, &u.Email, &u.PasswordHash, &u.Role, &u.CreatedAt)
-	return u, err
+		RETURNING `+userColumns,
+		name, email, passwordHash, role))
 }
 
 func (s *UserStore) GetByEmail(ctx context.Context, email string) (model.User, error) {
-	var u model.User
-	err := s.db.QueryRow(ctx, `
-		SELECT id, name, email, password_hash, role, created_at
+	u, err := scanUser(s.db.QueryRow(ctx, `
+		SELECT `+userColumns+`
 		FROM users
 		WHERE email = $1
-	`, email).Scan(&u.ID, &u.Name, &u.Email, &u.PasswordHash, &u.Role, &u.CreatedAt)
+	`, email))
 
 	if errors.Is(err, pgx.ErrNoRows) {
 		return model.User{}, pgx.ErrNoRows
@@ -44,12 +85,11 @@ func (s *UserStore) GetByEmail(ctx context.Context, email string) (model.User, e
 }
 
 func (s *UserStore) GetByID(ctx context.Context, id uuid.UUID) (model.User, error) {
-	var u model.User
-	err := s.db.QueryRow(ctx, `
-		SELECT id, name, email, password_hash, role, created_at
+	u, err := scanUser(s.db.QueryRow(ctx, `
+		SELECT `+userColumns+`
 		FROM users
 		WHERE id = $1
-	`, id).Scan(&u.ID, &u.Name, &u.Email, &u.PasswordHash, &u.Role, &u.CreatedAt)
+	`, id))
 
 	if errors.Is(err, pgx.ErrNoRows) {
 		return model.User{}, pgx.ErrNoRows
@@ -57,3 +97,234 @@ func (s *UserStore) GetByID(ctx context.Context, id uuid.UUID) (model.User, erro
 
 	return u, err
 }
+
+func (s *UserStore) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	tag, err := s.db.Exec(ctx, `
+		UPDATE users
+		SET password_hash = $2,
+			token_version = token_version + 1
+		WHERE id = $1
+	`, id, passwordHash)
+	s.ForgetStatus(id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+func (s *UserStore) MarkEmailVerified(ctx context.Context, id uuid.UUID) (model.User, error) {
+	u, err := scanUser(s.db.QueryRow(ctx, `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, now())
+		WHERE id = $1
+		RETURNING `+userColumns,
+		id))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.User{}, pgx.ErrNoRows
+	}
+	return u, err
+}
+
+func (s *UserStore) List(ctx context.Context, opt UserListOptions) ([]model.User, int, error) {
+	if opt.Page < 1 {
+		opt.Page = 1
+	}
+	if opt.Limit < 1 {
+		opt.Limit = 20
+	}
+	if opt.Limit > 100 {
+		opt.Limit = 100
+	}
+	offset := (opt.Page - 1) * opt.Limit
+
+	conds := []string{"1=1"}
+	args := []any{}
+	argN := 1
+
+	if q := strings.TrimSpace(opt.Q); q != "" {
+		conds = append(conds, fmt.Sprintf("(name ILIKE $%d OR email ILIKE $%d)", argN, argN))
+		args = append(args, "%"+q+"%")
+		argN++
+	}
+	if opt.Role != "" {
+		conds = append(conds, fmt.Sprintf("role = $%d", argN))
+		args = append(args, opt.Role)
+		argN++
+	}
+	if opt.Disabled != nil {
+		if *opt.Disabled {
+			conds = append(conds, "disabled_at IS NOT NULL")
+		} else {
+			conds = append(conds, "disabled_at IS NULL")
+		}
+	}
+
+	whereSQL := strings.Join(conds, " AND ")
+
+	var total int
+	if err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM users WHERE `+whereSQL, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, opt.Limit, offset)
+	rows, err := s.db.Query(ctx, `
+		SELECT `+userColumns+`
+		FROM users
+		WHERE `+whereSQL+`
+		ORDER BY created_at DESC, id
+		LIMIT $`+fmt.Sprint(argN)+` OFFSET $`+fmt.Sprint(argN+1), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	out := []model.User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return out, total, nil
+}
+
+// Update applies the non-nil fields of upd. An unknown role is reported as
+// ErrRoleNotFound.
+func (s *UserStore) Update(ctx context.Context, id uuid.UUID, upd UserUpdate) (model.User, error) {
+	u, err := scanUser(s.db.QueryRow(ctx, `
+		UPDATE users
+		SET name = COALESCE($2, name),
+			role = COALESCE($3, role),
+			disabled_at = CASE
+				WHEN $4::bool IS NULL THEN disabled_at
+				WHEN $4::bool THEN COALESCE(disabled_at, now())
+				ELSE NULL
+			END,
+			token_version = CASE
+				WHEN ($3::text IS NOT NULL AND $3::text <> role) OR $4::bool THEN token_version + 1
+				ELSE token_version
+			END
+		WHERE id = $1
+		RETURNING `+userColumns,
+		id, upd.Name, upd.Role, upd.Disabled))
+
+	s.ForgetStatus(id)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.User{}, pgx.ErrNoRows
+	}
+	if IsForeignKeyViolation(err) {
+		return model.User{}, ErrRoleNotFound
+	}
+	return u, err
+}
+
+func (s *UserStore) Delete(ctx context.Context, id uuid.UUID) (model.User, error) {
+	u, err := scanUser(s.db.QueryRow(ctx, `
+		DELETE FROM users
+		WHERE id = $1
+		RETURNING `+userColumns,
+		id))
+
+	s.ForgetStatus(id)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.User{}, pgx.ErrNoRows
+	}
+	return u, err
+}
+
+// Status reports whether the user still exists and is enabled. Results are
+// cached for userStatusTTL so it can be called on every authenticated request.
+func (s *UserStore) Status(ctx context.Context, id uuid.UUID) (UserStatus, error) {
+	s.mu.Lock()
+	e, ok := s.statuses[id]
+	s.mu.Unlock()
+	if ok && time.Since(e.loadedAt) < userStatusTTL {
+		return e.status, nil
+	}
+
+	var st UserStatus
+	err := s.db.QueryRow(ctx, `
+		SELECT true, disabled_at IS NOT NULL, token_version
+		FROM users
+		WHERE id = $1
+	`, id).Scan(&st.Exists, &st.Disabled, &st.TokenVersion)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return UserStatus{}, err
+	}
+
+	s.mu.Lock()
+	if len(s.statuses) > 10_000 {
+		s.statuses = map[uuid.UUID]userStatusEntry{}
+	}
+	s.statuses[id] = userStatusEntry{status: st, loadedAt: time.Now()}
+	s.mu.Unlock()
+
+	return st, nil
+}
+
+// ForgetStatus drops the cached status for id. Stores that bump the token
+// version outside UserStore call it so the new version takes effect at once.
+func (s *UserStore) ForgetStatus(id uuid.UUID) {
+	s.mu.Lock()
+	delete(s.statuses, id)
+	s.mu.Unlock()
+}
+
+// UpdateProfile changes the user's name and email. A changed email is marked
+// unverified and bumps the token version, since access tokens carry the
+// verification state.
+func (s *UserStore) UpdateProfile(ctx context.Context, id uuid.UUID, name, email *string) (model.User, error) {
+	u, err := scanUser(s.db.QueryRow(ctx, `
+		UPDATE users
+		SET name = COALESCE($2, name),
+			email = COALESCE($3, email),
+			email_verified_at = CASE
+				WHEN $3::text IS NOT NULL AND $3::text <> email THEN NULL
+				ELSE email_verified_at
+			END,
+			token_version = CASE
+				WHEN $3::text IS NOT NULL AND $3::text <> email THEN token_version + 1
+				ELSE token_version
+			END
+		WHERE id = $1
+		RETURNING `+userColumns,
+		id, name, email))
+
+	s.ForgetStatus(id)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.User{}, pgx.ErrNoRows
+	}
+	return u, err
+}
+
+// LastWithPermission reports whether id is the only enabled user whose role
+// grants perm.
+func (s *UserStore) LastWithPermission(ctx context.Context, id uuid.UUID, perm string) (bool, error) {
+	var last bool
+	err := s.db.QueryRow(ctx, `
+		SELECT EXISTS(
+				SELECT 1 FROM users u
+				JOIN role_permissions rp ON rp.role_name = u.role
+				WHERE u.id = $1 AND rp.permission = $2
+			)
+			AND NOT EXISTS(
+				SELECT 1 FROM users u
+				JOIN role_permissions rp ON rp.role_name = u.role
+				WHERE u.id <> $1 AND rp.permission = $2 AND u.disabled_at IS NULL
+			)
+	`, id, perm).Scan(&last)
+	return last, err
+}
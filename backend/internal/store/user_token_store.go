@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"mini-product-catalog/internal/model"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type UserTokenStore struct {
+	db *pgxpool.Pool
+}
+
+func NewUserTokenStore(db *pgxpool.Pool) *UserTokenStore {
+	return &UserTokenStore{db: db}
+}
+
+const userTokenColumns = `id, user_id, purpose, expires_at, used_at, created_at`
+
+func scanUserToken(row pgx.Row) (model.UserToken, error) {
+	var t model.UserToken
+	err := row.Scan(&t.ID, &t.UserID, &t.Purpose, &t.ExpiresAt, &t.UsedAt, &t.CreatedAt)
+	return t, err
+}
+
+// Create issues a new token and invalidates any unused token the user still
+// has for the same purpose, so only the most recent link works.
+func (s *UserTokenStore) Create(ctx context.Context, userID uuid.UUID, purpose, tokenHash string, expiresAt time.Time) (model.UserToken, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return model.UserToken{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE user_tokens
+		SET used_at = now()
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+	`, userID, purpose); err != nil {
+		return model.UserToken{}, err
+	}
+
+	t, err := scanUserToken(tx.QueryRow(ctx, `
+		INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+userTokenColumns,
+		userID, purpose, tokenHash, expiresAt))
+	if err != nil {
+		return model.UserToken{}, err
+	}
+
+	return t, tx.Commit(ctx)
+}
+
+// Consume marks an unused, unexpired token as used. It returns pgx.ErrNoRows
+// if no such token exists.
+func (s *UserTokenStore) Consume(ctx context.Context, purpose, tokenHash string) (model.UserToken, error) {
+	t, err := scanUserToken(s.db.QueryRow(ctx, `
+		UPDATE user_tokens
+		SET used_at = now()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > now()
+		RETURNING `+userTokenColumns,
+		tokenHash, purpose))
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.UserToken{}, pgx.ErrNoRows
+	}
+	return t, err
+}
+
+func (s *UserTokenStore) LastIssuedAt(ctx context.Context, userID uuid.UUID, purpose string) (*time.Time, error) {
+	var at *time.Time
+	err := s.db.QueryRow(ctx, `
+		SELECT max(created_at)
+		FROM user_tokens
+		WHERE user_id = $1 AND purpose = $2
+	`, userID, purpose).Scan(&at)
+	return at, err
+}
@@ -0,0 +1,298 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mini-product-catalog/internal/model"
+	"mini-product-catalog/internal/money"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrSKUTaken means another product or variant already uses the SKU.
+var ErrSKUTaken = errors.New("sku already exists")
+
+// ErrInvalidVariantOptions wraps the reason a variant's option values don't
+// fit its product's options.
+var ErrInvalidVariantOptions = errors.New("invalid variant options")
+
+// ErrOptionsInUse means an options change would orphan existing variants.
+var ErrOptionsInUse = errors.New("options are used by existing variants")
+
+type VariantStore struct {
+	db *pgxpool.Pool
+}
+
+func NewVariantStore(db *pgxpool.Pool) *VariantStore {
+	return &VariantStore{db: db}
+}
+
+const variantColumns = `v.id, v.product_id, v.sku, v.options, v.price, v.currency, v.stock_on_hand, v.stock_reserved, v.created_at, v.updated_at`
+
+func scanVariant(row pgx.Row) (model.ProductVariant, error) {
+	var v model.ProductVariant
+	var price pgtype.Numeric
+	var currency string
+	if err := row.Scan(&v.ID, &v.ProductID, &v.SKU, &v.Options, &price, &currency, &v.StockOnHand, &v.StockReserved, &v.CreatedAt, &v.UpdatedAt); err != nil {
+		return model.ProductVariant{}, err
+	}
+
+	var err error
+	v.Price, err = moneyFromNumeric(price, currency)
+	if err != nil {
+		return model.ProductVariant{}, err
+	}
+	v.InStock = v.StockOnHand > v.StockReserved
+	return v, nil
+}
+
+// claimSKU serializes writers of one SKU and fails with ErrSKUTaken when a
+// product or variant other than the ones being written already has it.
+// Products and variants share one SKU namespace.
+func claimSKU(ctx context.Context, tx pgx.Tx, sku string, productID, variantID uuid.UUID) error {
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('sku:' || $1))`, sku); err != nil {
+		return err
+	}
+
+	var taken bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM products WHERE sku = $1 AND id <> $2)
+			OR EXISTS(SELECT 1 FROM product_variants WHERE sku = $1 AND id <> $3)
+	`, sku, productID, variantID).Scan(&taken)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrSKUTaken
+	}
+	return nil
+}
+
+// CheckVariantOptions reports whether values picks exactly one allowed value
+// for each of the product's options and nothing else.
+func CheckVariantOptions(options []model.ProductOption, values map[string]string) error {
+	if len(values) != len(options) {
+		return fmt.Errorf("options must set exactly %d value(s), one per product option", len(options))
+	}
+	for _, o := range options {
+		v, ok := values[o.Name]
+		if !ok {
+			return fmt.Errorf("missing value for option %q", o.Name)
+		}
+		if !slices.Contains(o.Values, v) {
+			return fmt.Errorf("%q is not a value of option %q", v, o.Name)
+		}
+	}
+	return nil
+}
+
+const productOptionsQuery = `
+	SELECT name, "values"
+	FROM product_options
+	WHERE product_id = $1
+	ORDER BY position ASC, name ASC`
+
+func scanOptions(rows pgx.Rows, err error) ([]model.ProductOption, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []model.ProductOption{}
+	for rows.Next() {
+		var o model.ProductOption
+		if err := rows.Scan(&o.Name, &o.Values); err != nil {
+			return nil, err
+		}
+		out = append(out, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (s *VariantStore) ListOptions(ctx context.Context, productID uuid.UUID) ([]model.ProductOption, error) {
+	return scanOptions(s.db.Query(ctx, productOptionsQuery, productID))
+}
+
+// SetOptions replaces a product's options. It fails with ErrOptionsInUse when
+// a variant would be left with an option name or value that no longer exists.
+func (s *VariantStore) SetOptions(ctx context.Context, productID uuid.UUID, options []model.ProductOption) error {
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		var exists int
+		if err := tx.QueryRow(ctx, `SELECT 1 FROM products WHERE id = $1 FOR UPDATE`, productID).Scan(&exists); err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(ctx, `SELECT options FROM product_variants WHERE product_id = $1`, productID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var opts map[string]string
+			if err := rows.Scan(&opts); err != nil {
+				return err
+			}
+			if err := CheckVariantOptions(options, opts); err != nil {
+				return ErrOptionsInUse
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM product_options WHERE product_id = $1`, productID); err != nil {
+			return err
+		}
+		for i, o := range options {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO product_options (product_id, name, position, "values")
+				VALUES ($1, $2, $3, $4)
+			`, productID, o.Name, i, o.Values)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *VariantStore) List(ctx context.Context, productID uuid.UUID) ([]model.ProductVariant, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT `+variantColumns+`
+		FROM product_variants v
+		WHERE v.product_id = $1
+		ORDER BY v.created_at ASC, v.id ASC
+	`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []model.ProductVariant{}
+	for rows.Next() {
+		v, err := scanVariant(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (s *VariantStore) Get(ctx context.Context, productID, id uuid.UUID) (model.ProductVariant, error) {
+	return scanVariant(s.db.QueryRow(ctx, `
+		SELECT `+variantColumns+`
+		FROM product_variants v
+		WHERE v.id = $1 AND v.product_id = $2
+	`, id, productID))
+}
+
+// checkVariant locks the parent product against option and currency changes
+// and checks the variant against them. A missing product is pgx.ErrNoRows.
+func checkVariant(ctx context.Context, tx pgx.Tx, productID uuid.UUID, options map[string]string, price money.Money) error {
+	var currency string
+	if err := tx.QueryRow(ctx, `SELECT currency FROM products WHERE id = $1 FOR SHARE`, productID).Scan(&currency); err != nil {
+		return err
+	}
+	if price.Currency != currency {
+		return ErrVariantCurrency
+	}
+
+	productOptions, err := scanOptions(tx.Query(ctx, productOptionsQuery, productID))
+	if err != nil {
+		return err
+	}
+	if err := CheckVariantOptions(productOptions, options); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidVariantOptions, err)
+	}
+	return nil
+}
+
+func (s *VariantStore) Create(ctx context.Context, productID uuid.UUID, sku string, options map[string]string, price money.Money) (model.ProductVariant, error) {
+	var v model.ProductVariant
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if err := checkVariant(ctx, tx, productID, options, price); err != nil {
+			return err
+		}
+		if err := claimSKU(ctx, tx, sku, uuid.Nil, uuid.Nil); err != nil {
+			return err
+		}
+
+		var err error
+		v, err = scanVariant(tx.QueryRow(ctx, `
+			INSERT INTO product_variants AS v (product_id, sku, options, price, currency)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING `+variantColumns,
+			productID, sku, options, numeric(price), price.Currency))
+		return err
+	})
+
+	return v, err
+}
+
+func (s *VariantStore) Update(ctx context.Context, productID, id uuid.UUID, sku string, options map[string]string, price money.Money) (model.ProductVariant, error) {
+	var v model.ProductVariant
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if err := checkVariant(ctx, tx, productID, options, price); err != nil {
+			return err
+		}
+		if err := claimSKU(ctx, tx, sku, uuid.Nil, id); err != nil {
+			return err
+		}
+
+		var err error
+		v, err = scanVariant(tx.QueryRow(ctx, `
+			UPDATE product_variants v
+			SET sku = $3,
+				options = $4,
+				price = $5,
+				currency = $6,
+				updated_at = now()
+			WHERE id = $1 AND product_id = $2
+			RETURNING `+variantColumns,
+			id, productID, sku, options, numeric(price), price.Currency))
+		return err
+	})
+
+	return v, err
+}
+
+// Delete removes a variant unless stock is reserved for it, which is
+// ErrStockReserved. Its stock movements stay in the ledger.
+func (s *VariantStore) Delete(ctx context.Context, productID, id uuid.UUID) (bool, error) {
+	ct, err := s.db.Exec(ctx, `
+		DELETE FROM product_variants
+		WHERE id = $1 AND product_id = $2 AND stock_reserved = 0
+	`, id, productID)
+	if err != nil {
+		return false, err
+	}
+	if ct.RowsAffected() > 0 {
+		return true, nil
+	}
+
+	var exists bool
+	err = s.db.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM product_variants WHERE id = $1 AND product_id = $2)
+	`, id, productID).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		return false, ErrStockReserved
+	}
+	return false, nil
+}
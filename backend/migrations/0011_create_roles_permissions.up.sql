@@ -0,0 +1,51 @@
+CREATE TABLE IF NOT EXISTS roles (
+    name TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    name TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_name TEXT NOT NULL REFERENCES roles(name) ON DELETE CASCADE ON UPDATE CASCADE,
+    permission TEXT NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+    PRIMARY KEY (role_name, permission)
+);
+
+INSERT INTO permissions (name, description) VALUES
+    ('products:read', 'View products in the admin area'),
+    ('products:write', 'Create, update and delete products'),
+    ('categories:read', 'View categories in the admin area'),
+    ('categories:write', 'Create, update and delete categories'),
+    ('users:read', 'View user accounts'),
+    ('users:write', 'Manage user accounts'),
+    ('roles:manage', 'Define roles and assign them to users'),
+    ('api_keys:manage', 'Issue and revoke API keys')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO roles (name, description) VALUES
+    ('admin', 'Full access'),
+    ('user', 'Regular customer account'),
+    ('catalog_editor', 'Manages products'),
+    ('viewer', 'Read-only admin access')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_name, permission)
+SELECT 'admin', name FROM permissions
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role_name, permission) VALUES
+    ('catalog_editor', 'products:read'),
+    ('catalog_editor', 'products:write'),
+    ('catalog_editor', 'categories:read'),
+    ('viewer', 'products:read'),
+    ('viewer', 'categories:read'),
+    ('viewer', 'users:read')
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_fkey FOREIGN KEY (role) REFERENCES roles(name) ON UPDATE CASCADE;
+CREATE INDEX IF NOT EXISTS idx_users_role ON users(role);
\ No newline at end of file
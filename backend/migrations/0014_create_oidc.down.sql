@@ -0,0 +1,7 @@
+DELETE FROM user_tokens WHERE purpose = 'oidc_login';
+ALTER TABLE user_tokens DROP CONSTRAINT IF EXISTS user_tokens_purpose_check;
+ALTER TABLE user_tokens ADD CONSTRAINT user_tokens_purpose_check
+    CHECK (purpose IN ('password_reset', 'email_verification'));
+
+DROP TABLE IF EXISTS user_identities;
+DROP TABLE IF EXISTS oidc_states;
\ No newline at end of file
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_products_search_vector;
+DROP TRIGGER IF EXISTS trg_categories_search_vector ON categories;
+DROP TRIGGER IF EXISTS trg_products_search_vector ON products;
+DROP FUNCTION IF EXISTS categories_search_vector_trigger();
+DROP FUNCTION IF EXISTS products_search_vector_trigger();
+DROP FUNCTION IF EXISTS product_search_vector(TEXT, TEXT, UUID);
+ALTER TABLE products DROP COLUMN IF EXISTS search_vector;
\ No newline at end of file
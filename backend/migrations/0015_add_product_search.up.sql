@@ -0,0 +1,40 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+-- 'simple' avoids language-specific stemming; queries use prefix matching instead.
+CREATE OR REPLACE FUNCTION product_search_vector(p_name TEXT, p_description TEXT, p_category_id UUID)
+RETURNS tsvector AS $$
+    SELECT setweight(to_tsvector('simple', coalesce(p_name, '')), 'A')
+        || setweight(to_tsvector('simple', coalesce((SELECT name FROM categories WHERE id = p_category_id), '')), 'B')
+        || setweight(to_tsvector('simple', coalesce(p_description, '')), 'C');
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION products_search_vector_trigger() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector := product_search_vector(NEW.name, NEW.description, NEW.category_id);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_products_search_vector ON products;
+CREATE TRIGGER trg_products_search_vector
+    BEFORE INSERT OR UPDATE OF name, description, category_id ON products
+    FOR EACH ROW EXECUTE FUNCTION products_search_vector_trigger();
+
+CREATE OR REPLACE FUNCTION categories_search_vector_trigger() RETURNS trigger AS $$
+BEGIN
+    UPDATE products
+    SET search_vector = product_search_vector(name, description, category_id)
+    WHERE category_id = NEW.id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_categories_search_vector ON categories;
+CREATE TRIGGER trg_categories_search_vector
+    AFTER UPDATE OF name ON categories
+    FOR EACH ROW WHEN (OLD.name IS DISTINCT FROM NEW.name)
+    EXECUTE FUNCTION categories_search_vector_trigger();
+
+UPDATE products SET search_vector = product_search_vector(name, description, category_id);
+
+CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector);
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_products_price_id;
+DROP INDEX IF EXISTS idx_products_created_at_id;
\ No newline at end of file
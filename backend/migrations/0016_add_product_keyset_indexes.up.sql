@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_products_created_at_id ON products(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_products_price_id ON products(price, id);
\ No newline at end of file
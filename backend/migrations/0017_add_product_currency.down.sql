@@ -0,0 +1,2 @@
+ALTER TABLE products DROP COLUMN IF EXISTS currency;
+ALTER TABLE products ALTER COLUMN price TYPE NUMERIC(12,2);
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE products ALTER COLUMN price TYPE NUMERIC(15,3);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS currency TEXT NOT NULL DEFAULT 'IDR' CHECK (currency ~ '^[A-Z]{3}$');
\ No newline at end of file
@@ -0,0 +1,4 @@
+DELETE FROM permissions WHERE name = 'currencies:manage';
+DROP TABLE IF EXISTS product_prices;
+DROP TABLE IF EXISTS currency_rounding_rules;
+DROP TABLE IF EXISTS exchange_rates;
\ No newline at end of file
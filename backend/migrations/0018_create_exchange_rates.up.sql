@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    base_currency TEXT NOT NULL CHECK (base_currency ~ '^[A-Z]{3}$'),
+    quote_currency TEXT NOT NULL CHECK (quote_currency ~ '^[A-Z]{3}$'),
+    rate NUMERIC(20,10) NOT NULL CHECK (rate > 0),
+    effective_from TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (base_currency, quote_currency, effective_from),
+    CHECK (base_currency <> quote_currency)
+);
+
+CREATE TABLE IF NOT EXISTS currency_rounding_rules (
+    currency TEXT PRIMARY KEY CHECK (currency ~ '^[A-Z]{3}$'),
+    increment_minor BIGINT NOT NULL DEFAULT 1 CHECK (increment_minor > 0),
+    mode TEXT NOT NULL DEFAULT 'half_up' CHECK (mode IN ('half_up', 'up', 'down')),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS product_prices (
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    currency TEXT NOT NULL CHECK (currency ~ '^[A-Z]{3}$'),
+    price NUMERIC(15,3) NOT NULL CHECK (price >= 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (product_id, currency)
+);
+
+INSERT INTO permissions (name, description) VALUES
+    ('currencies:manage', 'Manage exchange rates and currency rounding')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_name, permission) VALUES
+    ('admin', 'currencies:manage')
+ON CONFLICT DO NOTHING;
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS product_slug_history;
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_slug_key;
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_sku_key;
+ALTER TABLE products DROP COLUMN IF EXISTS slug;
+ALTER TABLE products DROP COLUMN IF EXISTS sku;
\ No newline at end of file
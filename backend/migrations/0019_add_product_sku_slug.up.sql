@@ -0,0 +1,32 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS sku TEXT;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS slug TEXT;
+
+UPDATE products
+SET sku = 'SKU-' || upper(substr(replace(id::text, '-', ''), 1, 12))
+WHERE sku IS NULL;
+
+WITH s AS (
+    SELECT id, base, row_number() OVER (PARTITION BY base ORDER BY created_at, id) AS n
+    FROM (
+        SELECT id, created_at,
+            coalesce(nullif(trim(BOTH '-' FROM regexp_replace(lower(name), '[^a-z0-9]+', '-', 'g')), ''), 'product') AS base
+        FROM products
+    ) b
+)
+UPDATE products p
+SET slug = CASE WHEN s.n = 1 THEN s.base ELSE s.base || '-' || s.n END
+FROM s
+WHERE s.id = p.id AND p.slug IS NULL;
+
+ALTER TABLE products ALTER COLUMN sku SET NOT NULL;
+ALTER TABLE products ALTER COLUMN slug SET NOT NULL;
+ALTER TABLE products ADD CONSTRAINT products_sku_key UNIQUE (sku);
+ALTER TABLE products ADD CONSTRAINT products_slug_key UNIQUE (slug);
+
+CREATE TABLE IF NOT EXISTS product_slug_history (
+    slug TEXT PRIMARY KEY,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_slug_history_product_id ON product_slug_history(product_id);
\ No newline at end of file
@@ -0,0 +1,6 @@
+DELETE FROM permissions WHERE name = 'inventory:manage';
+DROP TABLE IF EXISTS stock_movements;
+DROP INDEX IF EXISTS idx_products_in_stock;
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_stock_check;
+ALTER TABLE products DROP COLUMN IF EXISTS stock_reserved;
+ALTER TABLE products DROP COLUMN IF EXISTS stock_on_hand;
\ No newline at end of file
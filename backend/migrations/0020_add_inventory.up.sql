@@ -0,0 +1,32 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS stock_on_hand INT NOT NULL DEFAULT 0;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS stock_reserved INT NOT NULL DEFAULT 0;
+ALTER TABLE products ADD CONSTRAINT products_stock_check
+    CHECK (stock_on_hand >= 0 AND stock_reserved >= 0 AND stock_reserved <= stock_on_hand);
+
+CREATE INDEX IF NOT EXISTS idx_products_in_stock ON products(id) WHERE stock_on_hand > stock_reserved;
+
+CREATE TABLE IF NOT EXISTS stock_movements (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('receive', 'adjust', 'reserve', 'release', 'ship')),
+    on_hand_delta INT NOT NULL,
+    reserved_delta INT NOT NULL,
+    on_hand_after INT NOT NULL,
+    reserved_after INT NOT NULL,
+    reason TEXT NOT NULL,
+    reference TEXT,
+    actor_kind TEXT NOT NULL,
+    actor_id UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_movements_product_created ON stock_movements(product_id, created_at DESC);
+
+INSERT INTO permissions (name, description) VALUES
+    ('inventory:manage', 'Receive, adjust and reserve stock')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_name, permission) VALUES
+    ('admin', 'inventory:manage'),
+    ('catalog_editor', 'inventory:manage')
+ON CONFLICT DO NOTHING;
\ No newline at end of file
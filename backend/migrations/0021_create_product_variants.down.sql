@@ -0,0 +1,3 @@
+ALTER TABLE stock_movements DROP COLUMN IF EXISTS variant_id;
+DROP TABLE IF EXISTS product_variants;
+DROP TABLE IF EXISTS product_options;
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS categories_parent_name_key;
+ALTER TABLE categories ADD CONSTRAINT categories_name_key UNIQUE (name);
+DROP INDEX IF EXISTS idx_categories_parent_id;
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_parent_check;
+ALTER TABLE categories DROP COLUMN IF EXISTS parent_id;
\ No newline at end of file
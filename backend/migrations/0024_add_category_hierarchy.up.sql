@@ -0,0 +1,8 @@
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS parent_id UUID REFERENCES categories(id) ON DELETE RESTRICT;
+ALTER TABLE categories ADD CONSTRAINT categories_parent_check CHECK (parent_id <> id);
+CREATE INDEX IF NOT EXISTS idx_categories_parent_id ON categories(parent_id);
+
+-- Names only need to be unique among siblings now.
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS categories_parent_name_key
+    ON categories (COALESCE(parent_id, '00000000-0000-0000-0000-000000000000'::uuid), name);
\ No newline at end of file
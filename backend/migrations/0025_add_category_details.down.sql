@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_categories_position;
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_slug_key;
+ALTER TABLE categories DROP COLUMN IF EXISTS position;
+ALTER TABLE categories DROP COLUMN IF EXISTS is_visible;
+ALTER TABLE categories DROP COLUMN IF EXISTS image_url;
+ALTER TABLE categories DROP COLUMN IF EXISTS description;
+ALTER TABLE categories DROP COLUMN IF EXISTS slug;
\ No newline at end of file
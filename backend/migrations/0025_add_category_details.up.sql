@@ -0,0 +1,32 @@
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS slug TEXT;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS image_url TEXT;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS is_visible BOOLEAN NOT NULL DEFAULT true;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS position INT NOT NULL DEFAULT 0;
+
+WITH s AS (
+    SELECT id, base, row_number() OVER (PARTITION BY base ORDER BY created_at, id) AS n
+    FROM (
+        SELECT id, created_at,
+            coalesce(nullif(trim(BOTH '-' FROM regexp_replace(lower(name), '[^a-z0-9]+', '-', 'g')), ''), 'category') AS base
+        FROM categories
+    ) b
+)
+UPDATE categories c
+SET slug = CASE WHEN s.n = 1 THEN s.base ELSE s.base || '-' || s.n END
+FROM s
+WHERE s.id = c.id AND c.slug IS NULL;
+
+-- Keep the current storefront order, newest first, as the starting positions.
+WITH o AS (
+    SELECT id, row_number() OVER (ORDER BY created_at DESC, id) - 1 AS pos
+    FROM categories
+)
+UPDATE categories c
+SET position = o.pos
+FROM o
+WHERE o.id = c.id;
+
+ALTER TABLE categories ALTER COLUMN slug SET NOT NULL;
+ALTER TABLE categories ADD CONSTRAINT categories_slug_key UNIQUE (slug);
+CREATE INDEX IF NOT EXISTS idx_categories_position ON categories(position, name);
\ No newline at end of file
@@ -0,0 +1,11 @@
+DROP TRIGGER IF EXISTS trg_exchange_rates_sort_prices ON exchange_rates;
+DROP TRIGGER IF EXISTS trg_product_prices_sort_prices ON product_prices;
+DROP TRIGGER IF EXISTS trg_products_sort_prices ON products;
+DROP FUNCTION IF EXISTS exchange_rates_sort_prices_trigger();
+DROP FUNCTION IF EXISTS product_prices_sort_prices_trigger();
+DROP FUNCTION IF EXISTS products_sort_prices_trigger();
+DROP FUNCTION IF EXISTS refresh_due_product_sort_prices();
+DROP FUNCTION IF EXISTS refresh_product_sort_prices(UUID, TEXT[]);
+DROP TABLE IF EXISTS product_sort_prices_state;
+DROP INDEX IF EXISTS idx_products_currency;
+DROP TABLE IF EXISTS product_sort_prices;
\ No newline at end of file
@@ -0,0 +1,136 @@
+-- product_sort_prices holds each product's price in every currency a listing
+-- can filter, sort and bucket by: its stored price, its overrides, and its
+-- price converted at the current exchange rate (before rounding). Triggers
+-- keep it in step with products, overrides and rates; rates that only take
+-- effect later are picked up by refresh_due_product_sort_prices().
+CREATE TABLE IF NOT EXISTS product_sort_prices (
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    currency TEXT NOT NULL,
+    price NUMERIC NOT NULL,
+    PRIMARY KEY (product_id, currency)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_sort_prices_currency_price_id ON product_sort_prices(currency, price, product_id);
+
+-- Listings only need product_sort_prices when some product is stored in
+-- another currency than the one they compare in.
+CREATE INDEX IF NOT EXISTS idx_products_currency ON products(currency);
+
+CREATE TABLE IF NOT EXISTS product_sort_prices_state (
+    id BOOLEAN PRIMARY KEY DEFAULT true CHECK (id),
+    rates_as_of TIMESTAMPTZ NOT NULL
+);
+
+-- refresh_product_sort_prices recomputes the rows of p_product, or of every
+-- product stored in one of p_currencies. NULL matches everything.
+CREATE OR REPLACE FUNCTION refresh_product_sort_prices(p_product UUID, p_currencies TEXT[])
+RETURNS void AS $$
+    DELETE FROM product_sort_prices sp
+    USING products p
+    WHERE p.id = sp.product_id
+        AND (p_product IS NULL OR p.id = p_product)
+        AND (p_currencies IS NULL OR p.currency = ANY(p_currencies));
+
+    INSERT INTO product_sort_prices (product_id, currency, price)
+    SELECT pp.product_id, pp.currency, pp.price
+    FROM product_prices pp
+    JOIN products p ON p.id = pp.product_id
+    WHERE (p_product IS NULL OR p.id = p_product)
+        AND (p_currencies IS NULL OR p.currency = ANY(p_currencies));
+
+    -- Like CurrencyStore.Rate: the latest rate in this direction, else the
+    -- latest one in the other direction inverted.
+    WITH rates AS (
+        SELECT DISTINCT ON (from_currency, to_currency) from_currency, to_currency, rate
+        FROM (
+            SELECT base_currency AS from_currency, quote_currency AS to_currency, rate, false AS inverse, effective_from
+            FROM exchange_rates
+            UNION ALL
+            SELECT quote_currency, base_currency, 1 / rate, true, effective_from
+            FROM exchange_rates
+        ) r
+        WHERE effective_from <= now()
+        ORDER BY from_currency, to_currency, inverse, effective_from DESC
+    )
+    INSERT INTO product_sort_prices (product_id, currency, price)
+    SELECT p.id, p.currency, p.price
+    FROM products p
+    WHERE (p_product IS NULL OR p.id = p_product)
+        AND (p_currencies IS NULL OR p.currency = ANY(p_currencies))
+    UNION ALL
+    SELECT p.id, r.to_currency, p.price * r.rate
+    FROM products p
+    JOIN rates r ON r.from_currency = p.currency
+    WHERE (p_product IS NULL OR p.id = p_product)
+        AND (p_currencies IS NULL OR p.currency = ANY(p_currencies))
+    -- Overrides win.
+    ON CONFLICT (product_id, currency) DO NOTHING;
+$$ LANGUAGE sql;
+
+-- refresh_due_product_sort_prices catches up with rates whose effective_from
+-- has passed since the last call. Listings call it now and then.
+CREATE OR REPLACE FUNCTION refresh_due_product_sort_prices() RETURNS void AS $$
+DECLARE
+    since TIMESTAMPTZ;
+    due TEXT[];
+BEGIN
+    SELECT rates_as_of INTO since FROM product_sort_prices_state FOR UPDATE;
+
+    SELECT array_agg(DISTINCT c.currency) INTO due
+    FROM exchange_rates r
+    CROSS JOIN LATERAL (VALUES (r.base_currency), (r.quote_currency)) c(currency)
+    WHERE r.effective_from > since AND r.effective_from <= now();
+
+    IF due IS NOT NULL THEN
+        PERFORM refresh_product_sort_prices(NULL, due);
+    END IF;
+    UPDATE product_sort_prices_state SET rates_as_of = now();
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION products_sort_prices_trigger() RETURNS trigger AS $$
+BEGIN
+    PERFORM refresh_product_sort_prices(NEW.id, NULL);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_products_sort_prices ON products;
+CREATE TRIGGER trg_products_sort_prices
+    AFTER INSERT OR UPDATE OF price, currency ON products
+    FOR EACH ROW EXECUTE FUNCTION products_sort_prices_trigger();
+
+CREATE OR REPLACE FUNCTION product_prices_sort_prices_trigger() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM refresh_product_sort_prices(OLD.product_id, NULL);
+    ELSE
+        PERFORM refresh_product_sort_prices(NEW.product_id, NULL);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_product_prices_sort_prices ON product_prices;
+CREATE TRIGGER trg_product_prices_sort_prices
+    AFTER INSERT OR UPDATE OR DELETE ON product_prices
+    FOR EACH ROW EXECUTE FUNCTION product_prices_sort_prices_trigger();
+
+CREATE OR REPLACE FUNCTION exchange_rates_sort_prices_trigger() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM refresh_product_sort_prices(NULL, ARRAY[OLD.base_currency, OLD.quote_currency]);
+    ELSE
+        PERFORM refresh_product_sort_prices(NULL, ARRAY[NEW.base_currency, NEW.quote_currency]);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_exchange_rates_sort_prices ON exchange_rates;
+CREATE TRIGGER trg_exchange_rates_sort_prices
+    AFTER INSERT OR UPDATE OR DELETE ON exchange_rates
+    FOR EACH ROW EXECUTE FUNCTION exchange_rates_sort_prices_trigger();
+
+INSERT INTO product_sort_prices_state (rates_as_of) VALUES (now()) ON CONFLICT (id) DO NOTHING;
+SELECT refresh_product_sort_prices(NULL, NULL);
\ No newline at end of file
@@ -0,0 +1,6 @@
+DELETE FROM stock_movements WHERE product_id IS NULL;
+ALTER TABLE stock_movements DROP CONSTRAINT IF EXISTS stock_movements_product_id_fkey;
+ALTER TABLE stock_movements ADD CONSTRAINT stock_movements_product_id_fkey
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE;
+ALTER TABLE stock_movements ALTER COLUMN product_id SET NOT NULL;
+ALTER TABLE stock_movements DROP COLUMN IF EXISTS product_sku;
\ No newline at end of file
@@ -0,0 +1,10 @@
+-- The ledger outlives the products it records: deleting a product clears
+-- product_id, and product_sku still says which product a movement was for.
+ALTER TABLE stock_movements ADD COLUMN IF NOT EXISTS product_sku TEXT;
+UPDATE stock_movements m SET product_sku = p.sku FROM products p WHERE p.id = m.product_id AND m.product_sku IS NULL;
+ALTER TABLE stock_movements ALTER COLUMN product_sku SET NOT NULL;
+
+ALTER TABLE stock_movements ALTER COLUMN product_id DROP NOT NULL;
+ALTER TABLE stock_movements DROP CONSTRAINT IF EXISTS stock_movements_product_id_fkey;
+ALTER TABLE stock_movements ADD CONSTRAINT stock_movements_product_id_fkey
+    FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE SET NULL;
\ No newline at end of file
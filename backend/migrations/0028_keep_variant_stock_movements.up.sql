@@ -0,0 +1,8 @@
+-- Like product_id, variant_id is cleared rather than cascading when the
+-- variant goes, and variant_sku keeps which variant a movement was for.
+ALTER TABLE stock_movements ADD COLUMN IF NOT EXISTS variant_sku TEXT;
+UPDATE stock_movements m SET variant_sku = v.sku FROM product_variants v WHERE v.id = m.variant_id AND m.variant_sku IS NULL;
+
+ALTER TABLE stock_movements DROP CONSTRAINT IF EXISTS stock_movements_variant_id_fkey;
+ALTER TABLE stock_movements ADD CONSTRAINT stock_movements_variant_id_fkey
+    FOREIGN KEY (variant_id) REFERENCES product_variants(id) ON DELETE SET NULL;
\ No newline at end of file
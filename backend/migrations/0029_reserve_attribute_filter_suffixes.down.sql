@@ -0,0 +1 @@
+ALTER TABLE category_attributes DROP CONSTRAINT IF EXISTS category_attributes_key_filter_suffix;
\ No newline at end of file
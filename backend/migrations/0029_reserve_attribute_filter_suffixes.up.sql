@@ -0,0 +1,14 @@
+-- Keys ending in a range-filter suffix can't be filtered on with attr.<key>,
+-- since the suffix is read as the operator. Rename any that exist, along
+-- with the product values stored under them, before forbidding them.
+UPDATE products p
+SET attributes = (p.attributes - a.key) || jsonb_build_object(a.key || '_value', p.attributes -> a.key)
+FROM category_attributes a
+WHERE a.category_id = p.category_id
+  AND a.key ~ '_(gt|gte|lt|lte)$'
+  AND p.attributes ? a.key;
+
+UPDATE category_attributes SET key = key || '_value' WHERE key ~ '_(gt|gte|lt|lte)$';
+
+ALTER TABLE category_attributes ADD CONSTRAINT category_attributes_key_filter_suffix
+    CHECK (key !~ '_(gt|gte|lt|lte)$');
\ No newline at end of file
@@ -6,6 +6,7 @@ import { RegisterPage } from "./routes/RegisterPage";
 import { CatalogPage } from "./routes/CatalogPage";
 import { AdminPage } from "./routes/AdminPage";
 import { ProductDetailPage } from "./routes/ProductDetailPage";
+import { OIDCCallbackPage } from "./routes/OIDCCallbackPage";
 import { Route, Routes } from "react-router-dom";
 
 export default function App() {
@@ -15,6 +16,7 @@ export default function App() {
         <Route path="/" element={<CatalogPage />} />
         <Route path="/login" element={<LoginPage />} />
         <Route path="/register" element={<RegisterPage />} />
+        <Route path="/oidc/callback" element={<OIDCCallbackPage />} />
         <Route path="/products/:id" element={<ProductDetailPage />} />
 
         <Route element={<ProtectedRoute />}></Route>
@@ -7,6 +7,7 @@ import {
   Button,
   Chip,
 } from "@heroui/react";
+import { canUseAdmin } from "../lib/AuthContext";
 import { useAuth } from "../lib/useAuth";
 import type React from "react";
 
@@ -44,7 +45,7 @@ export function AppShell({ children }: { children: React.ReactNode }) {
                 </Chip>
                 <span className="text-sm text-slate-700">{user.email}</span>
               </NavbarItem>
-              {user.role === "admin" && (
+              {canUseAdmin(user) && (
                 <NavbarItem>
                   <Link to="/admin">Admin</Link>
                 </NavbarItem>
@@ -0,0 +1,20 @@
+// Renders search snippets from the API, where matches are wrapped in
+// <mark></mark>. The text is split on the markers rather than parsed as HTML,
+// so product content can never inject markup.
+export function Highlighted({ text }: { text: string }) {
+  const parts = text.split(/<mark>|<\/mark>/);
+
+  return (
+    <>
+      {parts.map((part, i) =>
+        i % 2 === 1 ? (
+          <mark key={i} className="bg-yellow-200 rounded-sm">
+            {part}
+          </mark>
+        ) : (
+          <span key={i}>{part}</span>
+        ),
+      )}
+    </>
+  );
+}
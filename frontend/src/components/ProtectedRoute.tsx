@@ -1,4 +1,5 @@
 import { Navigate, Outlet, useLocation } from "react-router-dom";
+import { canUseAdmin } from "../lib/AuthContext";
 import { useAuth } from "../lib/useAuth";
 
 export function ProtectedRoute({ requireAdmin }: { requireAdmin?: boolean }) {
@@ -10,7 +11,7 @@ export function ProtectedRoute({ requireAdmin }: { requireAdmin?: boolean }) {
   if (!user)
     return <Navigate to="/login" replace state={{ from: loc.pathname }} />;
 
-  if (requireAdmin && user.role !== "admin") return <Navigate to="/" replace />;
+  if (requireAdmin && !canUseAdmin(user)) return <Navigate to="/" replace />;
 
   return <Outlet />;
 }
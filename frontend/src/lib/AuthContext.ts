@@ -4,16 +4,29 @@ export type User = {
   id: string;
   name: string;
   email: string;
-  role: "admin" | "user";
+  role: string;
+  permissions?: string[];
   created_at: string;
 };
 
+export function hasPermission(user: User | null, permission: string) {
+  return user?.permissions?.includes(permission) ?? false;
+}
+
+export function canUseAdmin(user: User | null) {
+  return (
+    hasPermission(user, "products:write") ||
+    hasPermission(user, "categories:write")
+  );
+}
+
 export type AuthContextValue = {
   token: string | null;
   user: User | null;
   loading: boolean;
   login: (email: string, password: string) => Promise<void>;
   register: (name: string, email: string, password: string) => Promise<void>;
+  loginWithOIDCCode: (code: string) => Promise<void>;
   logout: () => void;
   refreshMe: () => Promise<void>;
 };
@@ -3,14 +3,43 @@ import { apiFetch, type SuccessEnvelope } from "./api";
 import { AuthContext, type User, type AuthContextValue } from "./AuthContext";
 
 const TOKEN_KEY = "access_token";
+const REFRESH_KEY = "refresh_token";
+const EXPIRES_KEY = "access_token_expires_at";
+
+type TokenResponse = {
+  access_token: string;
+  expires_at: string;
+  refresh_token: string;
+  refresh_expires_at: string;
+};
 
 export function AuthProvider({ children }: { children: React.ReactNode }) {
   const [token, setToken] = useState<string | null>(() =>
     localStorage.getItem(TOKEN_KEY),
   );
+  const [expiresAt, setExpiresAt] = useState<string | null>(() =>
+    localStorage.getItem(EXPIRES_KEY),
+  );
   const [user, setUser] = useState<User | null>(null);
   const [loading, setLoading] = useState(true);
 
+  function storeTokens(t: TokenResponse) {
+    localStorage.setItem(TOKEN_KEY, t.access_token);
+    localStorage.setItem(REFRESH_KEY, t.refresh_token);
+    localStorage.setItem(EXPIRES_KEY, t.expires_at);
+    setToken(t.access_token);
+    setExpiresAt(t.expires_at);
+  }
+
+  function clearTokens() {
+    localStorage.removeItem(TOKEN_KEY);
+    localStorage.removeItem(REFRESH_KEY);
+    localStorage.removeItem(EXPIRES_KEY);
+    setToken(null);
+    setExpiresAt(null);
+    setUser(null);
+  }
+
   async function refreshMe(overrideToken?: string) {
     const activeToken = overrideToken ?? token;
     if (!activeToken) {
@@ -23,14 +52,31 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
     setUser(res.data);
   }
 
+  async function refreshSession(): Promise<string> {
+    const refreshToken = localStorage.getItem(REFRESH_KEY);
+    if (!refreshToken) throw new Error("no refresh token");
+
+    const res = await apiFetch<SuccessEnvelope<TokenResponse>>(
+      "/auth/refresh",
+      {
+        method: "POST",
+        body: { refresh_token: refreshToken },
+      },
+    );
+    storeTokens(res.data);
+    return res.data.access_token;
+  }
+
   useEffect(() => {
     (async () => {
       try {
         await refreshMe();
       } catch {
-        localStorage.removeItem(TOKEN_KEY);
-        setToken(null);
-        setUser(null);
+        try {
+          await refreshMe(await refreshSession());
+        } catch {
+          clearTokens();
+        }
       } finally {
         setLoading(false);
       }
@@ -38,19 +84,42 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
     // eslint-disable-next-line
   }, []);
 
+  useEffect(() => {
+    if (!token || !expiresAt) return;
+
+    const delay = Math.max(
+      new Date(expiresAt).getTime() - Date.now() - 60_000,
+      0,
+    );
+    const id = window.setTimeout(() => {
+      refreshSession().catch(clearTokens);
+    }, delay);
+
+    return () => window.clearTimeout(id);
+    // eslint-disable-next-line
+  }, [token, expiresAt]);
+
   async function login(email: string, password: string) {
-    const res = await apiFetch<SuccessEnvelope<{ access_token: string }>>(
-      "/auth/login",
+    const res = await apiFetch<SuccessEnvelope<TokenResponse>>("/auth/login", {
+      method: "POST",
+      body: { email, password },
+    });
+
+    storeTokens(res.data);
+    await refreshMe(res.data.access_token);
+  }
+
+  async function loginWithOIDCCode(code: string) {
+    const res = await apiFetch<SuccessEnvelope<TokenResponse>>(
+      "/auth/oidc/exchange",
       {
         method: "POST",
-        body: { email, password },
+        body: { code },
       },
     );
 
-    const t = res.data.access_token;
-    localStorage.setItem(TOKEN_KEY, t);
-    setToken(t);
-    await refreshMe(t);
+    storeTokens(res.data);
+    await refreshMe(res.data.access_token);
   }
 
   async function register(name: string, email: string, password: string) {
@@ -63,9 +132,14 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
   }
 
   function logout() {
-    localStorage.removeItem(TOKEN_KEY);
-    setToken(null);
-    setUser(null);
+    const refreshToken = localStorage.getItem(REFRESH_KEY);
+    if (refreshToken) {
+      apiFetch("/auth/logout", {
+        method: "POST",
+        body: { refresh_token: refreshToken },
+      }).catch(() => {});
+    }
+    clearTokens();
   }
 
   const value = useMemo<AuthContextValue>(
@@ -75,6 +149,7 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
       loading,
       login,
       register,
+      loginWithOIDCCode,
       logout,
       refreshMe,
     }),
@@ -1,7 +1,8 @@
-export function formatIDR(n: number) {
+import type { Money } from "./types";
+
+export function formatMoney(m: Money) {
   return new Intl.NumberFormat("id-ID", {
     style: "currency",
-    currency: "IDR",
-    maximumFractionDigits: 0,
-  }).format(n);
+    currency: m.currency,
+  }).format(Number(m.amount));
 }
@@ -1,16 +1,94 @@
 export type Category = {
   id: string;
+  parent_id: string | null;
   name: string;
+  slug: string;
+  description: string;
+  image_url: string | null;
+  is_visible: boolean;
+  position: number;
+  created_at: string;
+};
+
+export type CategoryAttribute = {
+  id: string;
+  category_id: string;
+  key: string;
+  label: string;
+  type: "text" | "number" | "enum" | "boolean";
+  unit: string | null;
+  options: string[];
+  required: boolean;
+  position: number;
   created_at: string;
 };
 
+export type Money = {
+  amount: string;
+  currency: string;
+};
+
 export type Product = {
   id: string;
   category_id: string;
   category_name: string;
+  sku: string;
+  slug: string;
   name: string;
   description: string;
-  price: number;
+  price: Money;
+  stock_on_hand: number;
+  stock_reserved: number;
+  in_stock: boolean;
+  created_at: string;
+  updated_at: string;
+  attributes: Record<string, string | number | boolean>;
+  breadcrumbs?: { id: string; name: string }[];
+  primary_image: ProductImage | null;
+  images?: ProductImage[];
+  price_range?: { min: Money; max: Money };
+  options?: ProductOption[];
+  variants?: ProductVariant[];
+  highlights?: {
+    name: string;
+    description: string;
+  };
+};
+
+export type ProductImage = {
+  id: string;
+  product_id: string;
+  url: string;
+  thumbnails: Record<string, string>;
+  alt_text: string;
+  position: number;
+  is_primary: boolean;
+  content_type: string;
+  width: number;
+  height: number;
+  size_bytes: number;
+  created_at: string;
+};
+
+export type ProductOption = {
+  name: string;
+  values: string[];
+};
+
+export type ProductVariant = {
+  id: string;
+  product_id: string;
+  sku: string;
+  options: Record<string, string>;
+  price: Money;
+  stock_on_hand: number;
+  stock_reserved: number;
+  in_stock: boolean;
   created_at: string;
   updated_at: string;
 };
+
+export type ProductFacets = {
+  category?: { id: string; name: string; count: number }[];
+  price?: { min: Money; max: Money | null; count: number }[];
+};
@@ -11,13 +11,21 @@ import {
   SelectItem,
 } from "@heroui/react";
 import { apiFetch, type SuccessEnvelope } from "../lib/api";
-import { type Category, type Product } from "../lib/types";
-import { formatIDR } from "../lib/format";
+import { type Category, type Product, type ProductFacets } from "../lib/types";
+import { formatMoney } from "../lib/format";
+import { Highlighted } from "../components/Highlighted";
 
 function totalPages(total: number, limit: number) {
   return Math.max(1, Math.ceil(total / limit));
 }
 
+function priceLabel(p: Product) {
+  const range = p.price_range;
+  if (!range) return formatMoney(p.price);
+  if (range.min.amount === range.max.amount) return formatMoney(range.min);
+  return `${formatMoney(range.min)} – ${formatMoney(range.max)}`;
+}
+
 export function CatalogPage() {
   const [categories, setCategories] = useState<Category[]>([]);
 
@@ -36,11 +44,21 @@ export function CatalogPage() {
     total: number;
     page: number;
     limit: number;
+    facets?: ProductFacets;
   } | null>(null);
   const [loading, setLoading] = useState(false);
   const [error, setError] = useState<string | null>(null);
 
-  const categoryOptions = [{ id: "all", name: "All" }, ...categories];
+  const categoryCounts = new Map(
+    (meta?.facets?.category ?? []).map((f) => [f.id, f.count]),
+  );
+  const categoryOptions = [
+    { id: "all", name: "All" },
+    ...categories.map((c) => ({
+      id: c.id,
+      name: `${c.name} (${categoryCounts.get(c.id) ?? 0})`,
+    })),
+  ];
 
   useEffect(() => {
     apiFetch<SuccessEnvelope<Category[]>>("/categories")
@@ -53,13 +71,20 @@ export function CatalogPage() {
     p.set("page", String(page));
     p.set("limit", String(limit));
 
-    if (q.trim()) p.set("q", q.trim());
-    if (categoryID !== "all") p.set("category_id", categoryID);
+    if (q.trim()) {
+      p.set("q", q.trim());
+      p.set("highlight", "true");
+    }
+    if (categoryID !== "all") {
+      p.set("category_id", categoryID);
+      p.set("include_subcategories", "true");
+    }
     if (minPrice.trim()) p.set("min_price", minPrice.trim());
     if (maxPrice.trim()) p.set("max_price", maxPrice.trim());
 
     p.set("sort", sort);
     p.set("order", order);
+    p.set("facets", "category");
 
     return p.toString();
   }, [page, limit, q, categoryID, minPrice, maxPrice, sort, order]);
@@ -163,6 +188,7 @@ export function CatalogPage() {
           >
             <SelectItem key="created_at">created_at</SelectItem>
             <SelectItem key="price">price</SelectItem>
+            <SelectItem key="relevance">relevance</SelectItem>
           </Select>
 
           <Select
@@ -214,19 +240,40 @@ export function CatalogPage() {
           {items.map((p) => (
             <Card key={p.id} className="hover:shadow-md transition">
               <CardBody className="space-y-2">
+                {p.primary_image && (
+                  <img
+                    src={
+                      p.primary_image.thumbnails.medium ?? p.primary_image.url
+                    }
+                    alt={p.primary_image.alt_text || p.name}
+                    className="h-40 w-full rounded object-cover"
+                    loading="lazy"
+                  />
+                )}
                 <div className="text-xs text-slate-500">{p.category_name}</div>
 
                 <Link
                   to={`/products/${p.id}`}
                   className="font-semibold underline-offset-2 hover:underline"
                 >
-                  {p.name}
+                  {p.highlights ? (
+                    <Highlighted text={p.highlights.name} />
+                  ) : (
+                    p.name
+                  )}
                 </Link>
 
                 <div className="text-sm text-slate-700 line-clamp-2">
-                  {p.description}
+                  {p.highlights ? (
+                    <Highlighted text={p.highlights.description} />
+                  ) : (
+                    p.description
+                  )}
                 </div>
-                <div className="font-mono text-sm">{formatIDR(p.price)}</div>
+                <div className="font-mono text-sm">{priceLabel(p)}</div>
+                {!p.in_stock && (
+                  <div className="text-xs text-danger">Out of stock</div>
+                )}
               </CardBody>
             </Card>
           ))}
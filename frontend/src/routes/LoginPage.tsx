@@ -1,8 +1,19 @@
-import { useState } from "react";
-import { useNavigate, useLocation, Link } from "react-router-dom";
+import { useEffect, useState } from "react";
+import {
+  useNavigate,
+  useLocation,
+  useSearchParams,
+  Link,
+} from "react-router-dom";
 import { Card, CardBody, Input, Button } from "@heroui/react";
 import { useAuth } from "../lib/useAuth";
-import { ApiError } from "../lib/api";
+import { ApiError, apiFetch, API_URL, type SuccessEnvelope } from "../lib/api";
+
+type OIDCProvider = {
+  name: string;
+  display_name: string;
+  start_url: string;
+};
 
 export function LoginPage() {
   const { login } = useAuth();
@@ -11,8 +22,21 @@ export function LoginPage() {
 
   const [email, setEmail] = useState("admin@example.com");
   const [password, setPassword] = useState("password123");
-  const [error, setError] = useState<string | null>(null);
+  const [params] = useSearchParams();
+  const [error, setError] = useState<string | null>(params.get("error"));
   const [submitting, setSubmitting] = useState(false);
+  const [providers, setProviders] = useState<OIDCProvider[]>([]);
+
+  useEffect(() => {
+    apiFetch<SuccessEnvelope<OIDCProvider[]>>("/auth/oidc/providers")
+      .then((res) => setProviders(res.data))
+      .catch(() => setProviders([]));
+  }, []);
+
+  function startOIDC(p: OIDCProvider) {
+    const from = (loc.state as any)?.from ?? "/";
+    window.location.href = `${API_URL}${p.start_url}?redirect=${encodeURIComponent(from)}`;
+  }
 
   async function onSubmit(e: React.SubmitEvent) {
     e.preventDefault();
@@ -57,6 +81,21 @@ export function LoginPage() {
           </Button>
         </form>
 
+        {providers.length > 0 && (
+          <div className="space-y-2">
+            {providers.map((p) => (
+              <Button
+                key={p.name}
+                variant="bordered"
+                className="w-full"
+                onPress={() => startOIDC(p)}
+              >
+                Sign in with {p.display_name}
+              </Button>
+            ))}
+          </div>
+        )}
+
         <div className="text-sm">
           Belum punya akun?{" "}
           <Link className="underline" to="/register">
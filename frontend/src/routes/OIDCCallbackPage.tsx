@@ -0,0 +1,53 @@
+import { useEffect, useRef, useState } from "react";
+import { Link, useNavigate, useSearchParams } from "react-router-dom";
+import { Card, CardBody } from "@heroui/react";
+import { useAuth } from "../lib/useAuth";
+import { ApiError } from "../lib/api";
+
+export function OIDCCallbackPage() {
+  const { loginWithOIDCCode } = useAuth();
+  const nav = useNavigate();
+  const [params] = useSearchParams();
+  const [error, setError] = useState<string | null>(null);
+  const started = useRef(false);
+
+  useEffect(() => {
+    // The login code is single use, so guard against StrictMode's double effect.
+    if (started.current) return;
+    started.current = true;
+
+    const code = params.get("code");
+    if (!code) {
+      setError("Missing login code");
+      return;
+    }
+
+    const redirect = params.get("redirect") ?? "/";
+    loginWithOIDCCode(code)
+      .then(() =>
+        nav(redirect.startsWith("/") ? redirect : "/", { replace: true }),
+      )
+      .catch((e) => {
+        if (e instanceof ApiError) setError(e.message);
+        else setError("Login failed");
+      });
+    // eslint-disable-next-line
+  }, []);
+
+  return (
+    <Card className="max-w-md mx-auto">
+      <CardBody className="space-y-4">
+        {error ? (
+          <>
+            <div className="text-sm text-red-600">{error}</div>
+            <Link className="underline text-sm" to="/login">
+              Back to login
+            </Link>
+          </>
+        ) : (
+          <div className="text-sm text-slate-600">Signing you in…</div>
+        )}
+      </CardBody>
+    </Card>
+  );
+}
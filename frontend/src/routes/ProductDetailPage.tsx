@@ -3,7 +3,7 @@ import { useParams, Link } from "react-router-dom";
 import { Card, CardBody, Spinner, Button } from "@heroui/react";
 import { apiFetch, type SuccessEnvelope } from "../lib/api";
 import { type Product } from "../lib/types";
-import { formatIDR } from "../lib/format";
+import { formatMoney } from "../lib/format";
 
 export function ProductDetailPage() {
   const { id } = useParams();
@@ -61,10 +61,53 @@ export function ProductDetailPage() {
   return (
     <Card>
       <CardBody className="space-y-2">
-        <div className="text-xs text-slate-500">{item.category_name}</div>
+        {item.primary_image && (
+          <img
+            src={item.primary_image.url}
+            alt={item.primary_image.alt_text || item.name}
+            className="max-h-96 w-full rounded object-contain"
+          />
+        )}
+        {item.images && item.images.length > 1 && (
+          <div className="flex gap-2 overflow-x-auto">
+            {item.images.map((img) => (
+              <a key={img.id} href={img.url} target="_blank" rel="noreferrer">
+                <img
+                  src={img.thumbnails.small ?? img.url}
+                  alt={img.alt_text || item.name}
+                  className="h-16 w-16 rounded object-cover"
+                />
+              </a>
+            ))}
+          </div>
+        )}
+        <div className="text-xs text-slate-500">
+          {item.breadcrumbs?.map((b) => b.name).join(" › ") ??
+            item.category_name}
+        </div>
         <div className="text-xl font-semibold">{item.name}</div>
         <div className="text-sm text-slate-700">{item.description}</div>
-        <div className="font-mono">{formatIDR(item.price)}</div>
+        <div className="font-mono">{formatMoney(item.price)}</div>
+
+        {item.variants && item.variants.length > 0 && (
+          <div className="space-y-1 pt-2">
+            {item.variants.map((v) => (
+              <div key={v.id} className="flex justify-between text-sm">
+                <span>
+                  {(item.options ?? [])
+                    .map((o) => v.options[o.name])
+                    .join(" / ") || v.sku}
+                </span>
+                <span className="font-mono">
+                  {formatMoney(v.price)}
+                  {!v.in_stock && (
+                    <span className="text-xs text-danger"> · Out of stock</span>
+                  )}
+                </span>
+              </div>
+            ))}
+          </div>
+        )}
 
         <div className="pt-2">
           <Button as={Link} to="/" variant="flat">
@@ -7,12 +7,16 @@ import {
   ModalHeader,
   ModalBody,
   ModalFooter,
+  Select,
+  SelectItem,
+  Switch,
   Table,
   TableHeader,
   TableColumn,
   TableBody,
   TableRow,
   TableCell,
+  Textarea,
   useDisclosure,
 } from "@heroui/react";
 
@@ -34,13 +38,21 @@ export function CategoriesManager() {
   const [mode, setMode] = useState<FormMode>("create");
   const [active, setActive] = useState<Category | null>(null);
   const [name, setName] = useState("");
+  const [parentID, setParentID] = useState<string>("none");
+  const [slug, setSlug] = useState("");
+  const [description, setDescription] = useState("");
+  const [imageURL, setImageURL] = useState("");
+  const [visible, setVisible] = useState(true);
 
   async function load() {
     setLoading(true);
     setError(null);
 
     try {
-      const res = await apiFetch<SuccessEnvelope<Category[]>>("/categories");
+      const res = await apiFetch<SuccessEnvelope<Category[]>>(
+        "/categories?include_hidden=true",
+        { token },
+      );
       setItems(res.data);
     } catch (e: any) {
       setError(e.message ?? "Failed to load categories");
@@ -51,16 +63,33 @@ export function CategoriesManager() {
 
   useEffect(() => {
     load();
-  }, []);
+  }, [token]);
 
   const sorted = useMemo(() => {
     return [...items].sort((a, b) => a.name.localeCompare(b.name));
   }, [items]);
 
+  const names = new Map(items.map((c) => [c.id, c.name]));
+  const parentOptions = [
+    { id: "none", name: "(none)" },
+    ...sorted.filter((c) => c.id !== active?.id),
+  ];
+
+  // The API lists categories in display order; siblings are the categories
+  // sharing a parent, which is the set reordered together.
+  function siblings(c: Category) {
+    return items.filter((s) => s.parent_id === c.parent_id);
+  }
+
   function openCreate() {
     setMode("create");
     setActive(null);
     setName("");
+    setParentID("none");
+    setSlug("");
+    setDescription("");
+    setImageURL("");
+    setVisible(true);
     setError(null);
     setMessage(null);
     onOpen();
@@ -70,6 +99,11 @@ export function CategoriesManager() {
     setMode("edit");
     setActive(c);
     setName(c.name);
+    setParentID(c.parent_id ?? "none");
+    setSlug(c.slug);
+    setDescription(c.description);
+    setImageURL(c.image_url ?? "");
+    setVisible(c.is_visible);
     setError(null);
     setMessage(null);
     onOpen();
@@ -82,19 +116,28 @@ export function CategoriesManager() {
     if (!token) return setError("No token (please re-login)");
     if (!name.trim()) return setError("Name is required");
 
+    const body = {
+      name: name.trim(),
+      parent_id: parentID === "none" ? null : parentID,
+      slug: slug.trim(),
+      description: description.trim(),
+      image_url: imageURL.trim(),
+      is_visible: visible,
+    };
+
     try {
       if (mode === "create") {
         await apiFetch("/categories", {
           method: "POST",
           token,
-          body: { name: name.trim() },
+          body,
         });
         setMessage("Category created");
       } else {
         await apiFetch(`/categories/${active!.id}`, {
           method: "PUT",
           token,
-          body: { name: name.trim() },
+          body,
         });
         setMessage("Category updated");
       }
@@ -107,6 +150,30 @@ export function CategoriesManager() {
     }
   }
 
+  async function move(c: Category, delta: -1 | 1) {
+    setError(null);
+    setMessage(null);
+
+    if (!token) return setError("No token (please re-login)");
+
+    const ids = siblings(c).map((s) => s.id);
+    const i = ids.indexOf(c.id);
+    const j = i + delta;
+    if (j < 0 || j >= ids.length) return;
+    [ids[i], ids[j]] = [ids[j], ids[i]];
+
+    try {
+      const res = await apiFetch<SuccessEnvelope<Category[]>>(
+        "/categories/order",
+        { method: "PUT", token, body: { ids } },
+      );
+      setItems(res.data);
+    } catch (e) {
+      if (e instanceof ApiError) setError(e.message);
+      else setError("Failed to reorder");
+    }
+  }
+
   async function remove(c: Category) {
     setError(null);
     setMessage(null);
@@ -139,18 +206,44 @@ export function CategoriesManager() {
       <Table aria-label="categories-table" isStriped>
         <TableHeader>
           <TableColumn>Name</TableColumn>
-          <TableColumn width={220}>Actions</TableColumn>
+          <TableColumn>Slug</TableColumn>
+          <TableColumn>Parent</TableColumn>
+          <TableColumn>Visible</TableColumn>
+          <TableColumn width={300}>Actions</TableColumn>
         </TableHeader>
         <TableBody
-          items={sorted}
+          items={items}
           isLoading={loading}
           emptyContent={"No categories."}
         >
           {(c) => (
             <TableRow key={c.id}>
               <TableCell>{c.name}</TableCell>
+              <TableCell>{c.slug}</TableCell>
+              <TableCell>
+                {c.parent_id ? (names.get(c.parent_id) ?? "") : ""}
+              </TableCell>
+              <TableCell>{c.is_visible ? "Yes" : "No"}</TableCell>
               <TableCell>
                 <div className="flex gap-2">
+                  <Button
+                    size="sm"
+                    variant="flat"
+                    isIconOnly
+                    aria-label="Move up"
+                    onPress={() => move(c, -1)}
+                  >
+                    ↑
+                  </Button>
+                  <Button
+                    size="sm"
+                    variant="flat"
+                    isIconOnly
+                    aria-label="Move down"
+                    onPress={() => move(c, 1)}
+                  >
+                    ↓
+                  </Button>
                   <Button size="sm" variant="flat" onPress={() => openEdit(c)}>
                     Edit
                   </Button>
@@ -178,6 +271,36 @@ export function CategoriesManager() {
               </ModalHeader>
               <ModalBody className="space-y-2">
                 <Input label="Name" value={name} onValueChange={setName} />
+                <Select
+                  label="Parent"
+                  items={parentOptions}
+                  selectedKeys={new Set([parentID])}
+                  onSelectionChange={(keys) => {
+                    const v = Array.from(keys)[0] as string | undefined;
+                    setParentID(v ?? "none");
+                  }}
+                >
+                  {(c) => <SelectItem key={c.id}>{c.name}</SelectItem>}
+                </Select>
+                <Input
+                  label="Slug"
+                  description="Leave empty to derive it from the name"
+                  value={slug}
+                  onValueChange={setSlug}
+                />
+                <Textarea
+                  label="Description"
+                  value={description}
+                  onValueChange={setDescription}
+                />
+                <Input
+                  label="Image URL"
+                  value={imageURL}
+                  onValueChange={setImageURL}
+                />
+                <Switch isSelected={visible} onValueChange={setVisible}>
+                  Visible in storefront
+                </Switch>
               </ModalBody>
               <ModalFooter>
                 <Button variant="flat" onPress={onClose}>
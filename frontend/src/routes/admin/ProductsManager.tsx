@@ -23,7 +23,7 @@ import {
 import { apiFetch, type SuccessEnvelope, ApiError } from "../../lib/api";
 import type { Category, Product } from "../../lib/types";
 import { useAuth } from "../../lib/useAuth";
-import { formatIDR } from "../../lib/format";
+import { formatMoney } from "../../lib/format";
 
 type FormMode = "create" | "edit";
 
@@ -54,12 +54,16 @@ export function ProductsManager() {
   const [active, setActive] = useState<Product | null>(null);
 
   const [categoryID, setCategoryID] = useState<string>("all");
+  const [sku, setSKU] = useState("");
   const [name, setName] = useState("");
   const [description, setDescription] = useState<string>("");
   const [price, setPrice] = useState<string>("");
 
   async function loadCategories() {
-    const res = await apiFetch<SuccessEnvelope<Category[]>>("/categories");
+    const res = await apiFetch<SuccessEnvelope<Category[]>>(
+      "/categories?include_hidden=true",
+      { token },
+    );
     setCategories(res.data);
   }
 
@@ -69,7 +73,8 @@ export function ProductsManager() {
 
     try {
       const res = await apiFetch<SuccessEnvelope<Product[]>>(
-        `/products?page=${page}&limit=${limit}`,
+        `/products?page=${page}&limit=${limit}&include_hidden=true`,
+        { token },
       );
       setItems(res.data);
       setMeta(res.meta);
@@ -82,12 +87,13 @@ export function ProductsManager() {
 
   useEffect(() => {
     loadCategories().catch(() => {});
-  }, []);
+    // eslint-disable-next-line
+  }, [token]);
 
   useEffect(() => {
     loadProducts();
     // eslint-disable-next-line
-  }, [page]);
+  }, [page, token]);
 
   const pages = totalPages(meta?.total ?? 0, limit);
 
@@ -99,6 +105,7 @@ export function ProductsManager() {
     setMode("create");
     setActive(null);
     setCategoryID(categoryOptions[0]?.id ?? "all");
+    setSKU("");
     setName("");
     setDescription("");
     setPrice("");
@@ -111,9 +118,10 @@ export function ProductsManager() {
     setMode("edit");
     setActive(p);
     setCategoryID(p.category_id);
+    setSKU(p.sku);
     setName(p.name);
     setDescription(p.description);
-    setPrice(String(p.price));
+    setPrice(p.price.amount);
     setError(null);
     setMessage(null);
     onOpen();
@@ -126,6 +134,7 @@ export function ProductsManager() {
     if (!token) return setError("No token (please re-login)");
     if (!categoryID || categoryID === "all")
       return setError("Category is required");
+    if (!sku.trim()) return setError("SKU is required");
     if (!name.trim()) return setError("Name is required");
 
     const priceNum = Number(price);
@@ -134,9 +143,17 @@ export function ProductsManager() {
 
     const body = {
       category_id: categoryID,
+      sku: sku.trim(),
       name: name.trim(),
       description: description ?? "",
-      price: priceNum,
+      price: {
+        amount: price.trim(),
+        currency: active?.price.currency ?? "IDR",
+      },
+      // Attribute values belong to the category's schema, so they only carry
+      // over while the category stays the same.
+      attributes:
+        active && active.category_id === categoryID ? active.attributes : {},
     };
 
     try {
@@ -208,7 +225,7 @@ export function ProductsManager() {
                   <TableCell>{p.name}</TableCell>
                   <TableCell>{p.category_name}</TableCell>
                   <TableCell className="font-mono">
-                    {formatIDR(p.price)}
+                    {formatMoney(p.price)}
                   </TableCell>
                   <TableCell>
                     <div className="flex gap-2">
@@ -261,6 +278,7 @@ export function ProductsManager() {
                   ))}
                 </Select>
 
+                <Input label="SKU" value={sku} onValueChange={setSKU} />
                 <Input label="Name" value={name} onValueChange={setName} />
                 <Textarea
                   label="Description"